@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"manimatic/internal/api"
+	"manimatic/internal/api/session"
 	"manimatic/internal/awsutils"
 	"manimatic/internal/config"
 	"manimatic/internal/llm"
 	"manimatic/internal/llm/openai"
-	"manimatic/internal/llm/xai"
+	"manimatic/internal/llm/providers"
 	"manimatic/internal/logger"
+	"manimatic/internal/store"
+	"manimatic/pkg/storage"
 	"net/http"
 	"os"
 	"os/signal"
@@ -27,21 +31,74 @@ func main() {
 	}
 	logger := logger.NewLogger(cfg)
 	logger.Info(cfg.Processing.Features.String())
-	llmService := llm.NewService(string(openai.ChatModelGPT4o))
-	openai.RegisterWith(llmService, cfg.OpenAI.Key)
-	xai.RegisterWith(llmService, cfg.XAI.Key)
+	llmService, discoverers, err := providers.NewService(cfg, logger)
+	if err != nil {
+		log.Fatalf("Error creating LLM service %s \n", err.Error())
+	}
+	switch cfg.LLM.Cache {
+	case "memory":
+		llmService.SetCache(llm.NewMemoryCache(cfg.LLM.CacheSize, cfg.LLM.CacheTTL))
+	case "file":
+		cache, err := llm.NewFileCache(cfg.LLM.CacheDir, cfg.LLM.CacheSize, cfg.LLM.CacheTTL)
+		if err != nil {
+			log.Fatalf("Error creating response cache %s \n", err.Error())
+		}
+		llmService.SetCache(cache)
+	case "none", "":
+	default:
+		logger.Error("unknown response cache backend, caching disabled", "cache", cfg.LLM.Cache)
+	}
 	awsConfig, err := awsconfig.LoadDefaultConfig(context.TODO())
 	if err != nil {
 		log.Fatal(err)
 	}
 	sqsClient := awsutils.NewSQSClient(*cfg, awsConfig)
-	api := api.New(cfg, logger, llmService, sqsClient)
+	var moderator llm.Moderator
+	if cfg.Processing.EnableModeration {
+		switch cfg.Processing.ModerationBackend {
+		case "rules":
+			moderator, err = llm.LoadRuleModerator(cfg.Processing.ModerationRules)
+			if err != nil {
+				log.Fatalf("Error loading moderation rules %s \n", err.Error())
+			}
+		case "openai":
+			moderator = openai.NewModerator(cfg.OpenAI.Key)
+		default:
+			log.Fatalf("Unknown moderation backend %s \n", cfg.Processing.ModerationBackend)
+		}
+	}
+	var history store.Store
+	switch cfg.History.Store {
+	case "sqlite":
+		db, err := store.OpenSQLite(cfg.History.Path)
+		if err != nil {
+			log.Fatalf("Error opening history store %s \n", err.Error())
+		}
+		defer db.Close()
+		history = db
+	case "memory":
+		history = store.NewMemory()
+	}
+	videos := storage.NewS3(awsutils.NewS3Client(*cfg, awsConfig), cfg.AWS.VideoBucketName, logger)
+	sessions, closeSessions, err := session.OpenStore(cfg.Session)
+	if err != nil {
+		log.Fatalf("Error opening session store %s \n", err.Error())
+	}
+	defer closeSessions()
+	api := api.New(cfg, logger, llmService, moderator, sqsClient, history, videos, videos, sessions)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	api.StartMessageProcessor(ctx)
 
+	if cfg.LLM.PromptsDir != "" {
+		go reloadPromptsOnHangup(ctx, logger, llmService.Prompts(), cfg.LLM.PromptsDir)
+	}
+	if len(discoverers) > 0 {
+		go discoverModels(ctx, cfg, logger, llmService, discoverers)
+	}
+
 	server := http.Server{
 		Addr:              fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 		Handler:           api,
@@ -75,3 +132,43 @@ func main() {
 
 	logger.Info("Server has shut down gracefully")
 }
+
+// reloadPromptsOnHangup reloads the prompt templates from dir on every SIGHUP,
+// keeping the current ones if the directory is invalid.
+func reloadPromptsOnHangup(ctx context.Context, logger *slog.Logger, prompts *llm.PromptRegistry, dir string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := prompts.LoadDir(dir); err != nil {
+				logger.Error("failed to reload prompts", "dir", dir, "error", err)
+				continue
+			}
+			logger.Info("reloaded prompts", "dir", dir)
+		}
+	}
+}
+
+// discoverModels lists the models of the discoverers and refreshes them every
+// DiscoveryInterval. It runs in the background so that slow provider APIs
+// don't delay the startup.
+func discoverModels(ctx context.Context, cfg *config.Config, logger *slog.Logger, llmService *llm.Service, discoverers []*llm.Discoverer) {
+	providers.Discover(ctx, cfg, logger, llmService, discoverers)
+	if cfg.LLM.DiscoveryInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.LLM.DiscoveryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			providers.SyncModels(ctx, logger, llmService, discoverers)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Command eval runs a suite of prompts through the configured models and
+// reports how many scripts pass validation and, with -compile, compile. The
+// LLM is configured like the API, e.g. LLM_MODE=fake for CI; compiling runs
+// the manim found in PATH, fixtures/bin holds a stand-in for CI.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"manimatic/internal/config"
+	"manimatic/internal/eval"
+	"manimatic/internal/llm"
+	"manimatic/internal/llm/providers"
+	"manimatic/internal/logger"
+	"manimatic/internal/worker/manimexec"
+	"manimatic/internal/worker/manimexec/security"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	suitePath := flag.String("suite", "fixtures/eval.jsonl", "JSONL file of cases with id, prompt and expect_invalid fields")
+	modelsList := flag.String("models", "", "Comma-separated models to evaluate, the default model if empty")
+	compile := flag.Bool("compile", false, "Compile the scripts that pass validation with manim")
+	out := flag.String("out", "eval-report.json", "Path of the JSON report, - for stdout (the table then goes to stderr)")
+	promptID := flag.String("prompt", "", "System prompt template to evaluate instead of the configured one")
+	concurrency := flag.Int("concurrency", 4, "Cases evaluated at once")
+	minPassRate := flag.Float64("min-pass-rate", 0, "Exit with status 1 when a model passes fewer cases than this rate (0-1)")
+	var params llm.Params
+	flag.Func("temperature", "Sampling temperature of every generation", func(s string) error {
+		v, err := strconv.ParseFloat(s, 64)
+		params.Temperature = &v
+		return err
+	})
+	flag.Func("seed", "Sampling seed of every generation", func(s string) error {
+		v, err := strconv.ParseInt(s, 10, 64)
+		params.Seed = &v
+		return err
+	})
+
+	// Parses the flags above along with the configuration ones
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Error loading config %s \n", err.Error())
+	}
+	logger := logger.NewLogger(cfg)
+
+	cases, err := eval.LoadSuite(*suitePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	llmService, discoverers, err := providers.NewService(cfg, logger)
+	if err != nil {
+		log.Fatalf("Error creating LLM service %s \n", err.Error())
+	}
+	providers.Discover(context.Background(), cfg, logger, llmService, discoverers)
+	if *promptID != "" {
+		if err := llmService.Prompts().SetDefault(*promptID); err != nil {
+			log.Fatal(err)
+		}
+	}
+	models := []string{llmService.DefaultModel()}
+	if *modelsList != "" {
+		models = strings.Split(*modelsList, ",")
+	}
+	for _, model := range models {
+		if err := llmService.ValidateParams(model, params); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	runner := &eval.Runner{
+		Service:     llmService,
+		Validator:   security.NewValidator(nil),
+		Params:      params,
+		Timeout:     cfg.LLM.RequestTimeout,
+		Concurrency: *concurrency,
+	}
+	cleanup := func() {}
+	if *compile {
+		dir, err := os.MkdirTemp(cfg.Worker.BaseDir, "manimatic_eval_")
+		if err != nil {
+			log.Fatal(err)
+		}
+		cleanup = func() { os.RemoveAll(dir) }
+		defer cleanup()
+		cfg.Worker.BaseDir = dir
+		runner.Compiler = manimexec.MustNewExecutor(cfg)
+	}
+
+	logger.Info("running evaluation", "suite", *suitePath, "cases", len(cases), "models", models, "compile", *compile)
+	results := runner.Run(context.Background(), cases, models)
+	report := eval.NewReport(*suitePath, *compile, models, results)
+
+	if err := writeReport(report, *out); err != nil {
+		log.Fatal(err)
+	}
+	for _, s := range report.Models {
+		if s.PassRate < *minPassRate {
+			logger.Error("pass rate below minimum", "model", s.Model, "pass_rate", s.PassRate, "min", *minPassRate)
+			cleanup()
+			os.Exit(1)
+		}
+	}
+}
+
+// writeReport writes the JSON report to out and the table to stdout, or to
+// stderr when the JSON goes to stdout.
+func writeReport(report eval.Report, out string) error {
+	table := os.Stdout
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	data = append(data, '\n')
+	switch out {
+	case "":
+	case "-":
+		table = os.Stderr
+		if _, err := os.Stdout.Write(data); err != nil {
+			return err
+		}
+	default:
+		if err := os.WriteFile(out, data, 0644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	return report.WriteTable(table)
+}
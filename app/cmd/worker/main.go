@@ -34,7 +34,7 @@ func main() {
 	S3Storage := storage.NewS3(s3Client, cfg.AWS.VideoBucketName, log)
 	msgQueue := queue.NewSQS(sqsClient, cfg.AWS.TaskQueueURL, cfg.AWS.ResultQueueURL, log)
 	q := animation.NewQueue(msgQueue, log)
-	workerService, err := worker.NewWorkerService(cfg, q, S3Storage, log)
+	workerService, err := worker.NewWorkerService(cfg, q, S3Storage, S3Storage, log)
 	if err != nil {
 		fmt.Println("Failed to create worker service:", err)
 		os.Exit(1)
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"io"
+	"manimatic/internal/api/events"
+	"strings"
+	"sync"
+)
+
+// activeJobs tracks the latest generation or compilation of each session.
+// Starting a new one supersedes the previous: its LLM calls are canceled
+// and events it still produces are dropped.
+type activeJobs struct {
+	mu       sync.Mutex
+	sessions map[string]activeJob
+}
+
+type activeJob struct {
+	id     string
+	ctx    context.Context // Canceled when the job is superseded or finished
+	cancel context.CancelFunc
+	queued bool // Sent to the worker for compilation
+}
+
+func newActiveJobs() *activeJobs {
+	return &activeJobs{sessions: make(map[string]activeJob)}
+}
+
+// start makes jobID the current job of the session and returns the context
+// its LLM calls run in. The previous job is canceled; if it had been queued
+// for compilation its ID is returned so the worker can be told to drop it.
+func (r *activeJobs) start(sessionID, jobID string) (context.Context, string) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev, ok := r.sessions[sessionID]
+	r.sessions[sessionID] = activeJob{id: jobID, ctx: ctx, cancel: cancel}
+	if !ok {
+		return ctx, ""
+	}
+	prev.cancel()
+	if !prev.queued {
+		return ctx, ""
+	}
+	return ctx, prev.id
+}
+
+// context returns the context of jobID if it is still the current job of
+// the session.
+func (r *activeJobs) context(sessionID, jobID string) (context.Context, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.sessions[sessionID]
+	if !ok || job.id != jobID {
+		return nil, false
+	}
+	return job.ctx, true
+}
+
+// current reports whether jobID is the latest job of the session. Events of
+// any other job are stale.
+func (r *activeJobs) current(sessionID, jobID string) bool {
+	_, ok := r.context(sessionID, jobID)
+	return ok
+}
+
+// queue marks jobID as sent to the worker. It returns false if the job was
+// superseded in the meantime and must not be queued.
+func (r *activeJobs) queue(sessionID, jobID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.sessions[sessionID]
+	if !ok || job.id != jobID {
+		return false
+	}
+	job.queued = true
+	r.sessions[sessionID] = job
+	return true
+}
+
+// finish forgets jobID if it is still the current job of the session.
+func (r *activeJobs) finish(sessionID, jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.sessions[sessionID]; ok && job.id == jobID {
+		job.cancel()
+		delete(r.sessions, sessionID)
+	}
+}
+
+// stop cancels the current job of the session. Like start, it returns the
+// job ID if the job had been queued for compilation.
+func (r *activeJobs) stop(sessionID string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.sessions[sessionID]
+	if !ok {
+		return ""
+	}
+	job.cancel()
+	delete(r.sessions, sessionID)
+	if !job.queued {
+		return ""
+	}
+	return job.id
+}
+
+// supersede makes jobID the current job of the session, canceling the LLM
+// calls and the compilation of the job it replaces. It returns the context
+// the new job's LLM calls must run in.
+func (a *App) supersede(sessionID, jobID string) context.Context {
+	ctx, prev := a.active.start(sessionID, jobID)
+	a.remember(a.records.cancel(sessionID, jobID)...)
+	if prev != "" {
+		a.cancelCompile(sessionID, prev)
+	}
+	return ctx
+}
+
+// CancelStore keeps the cancellations of compilations where every worker
+// sees them. A message on the task queue would only reach one of them.
+type CancelStore interface {
+	Upload(ctx context.Context, key string, file io.Reader) error
+}
+
+// cancelCompile tells the workers to drop or kill the compilation of jobID.
+func (a *App) cancelCompile(sessionID, jobID string) {
+	a.jobs.remove(jobID)
+	if a.cancels == nil {
+		return
+	}
+	a.logger.Info("canceling superseded compilation", "session_id", sessionID, "job_id", jobID)
+	go func() {
+		if err := a.cancels.Upload(context.TODO(), events.CancelKey(jobID), strings.NewReader(sessionID)); err != nil {
+			a.logger.Error("failed to cancel compilation", "error", err, "job_id", jobID)
+		}
+	}()
+}
+
+// notify sends an event of jobID to the client unless the job has been
+// superseded. It reports whether the event was sent.
+func (a *App) notify(sessionID, jobID string, ev events.Event) bool {
+	if !a.active.current(sessionID, jobID) {
+		a.logger.Debug("dropping event of superseded job", "kind", ev.Kind, "session_id", sessionID, "job_id", jobID)
+		return false
+	}
+	a.publish(ev.WithJobID(jobID))
+	return true
+}
+
+// failJob reports the failure of jobID to the client and forgets the job.
+func (a *App) failJob(sessionID, jobID string, ev events.Event) {
+	if a.notify(sessionID, jobID, ev) {
+		a.active.finish(sessionID, jobID)
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"io"
+	"manimatic/internal/api/events"
+	"testing"
+	"time"
+)
+
+func TestActiveJobs(t *testing.T) {
+	r := newActiveJobs()
+
+	first, prev := r.start("s", "1")
+	if prev != "" {
+		t.Errorf("unexpected superseded job %q", prev)
+	}
+	if !r.queue("s", "1") {
+		t.Fatal("expected current job to be queued")
+	}
+
+	second, prev := r.start("s", "2")
+	if prev != "1" {
+		t.Errorf("expected queued job 1 to be superseded, got %q", prev)
+	}
+	if first.Err() == nil {
+		t.Error("expected context of superseded job to be canceled")
+	}
+	if r.current("s", "1") || r.queue("s", "1") {
+		t.Error("superseded job must not be current")
+	}
+
+	// Job 2 never reached the worker, so there is nothing to cancel there
+	if _, prev := r.start("s", "3"); prev != "" || second.Err() == nil {
+		t.Errorf("expected job 2 to be canceled without a worker cancel, got %q", prev)
+	}
+
+	r.finish("s", "3")
+	if r.current("s", "3") {
+		t.Error("finished job must not be current")
+	}
+	if r.stop("s") != "" {
+		t.Error("expected no job to stop")
+	}
+}
+
+// cancelMarkers records the cancellations uploaded by the app.
+type cancelMarkers chan string
+
+func (c cancelMarkers) Upload(_ context.Context, key string, _ io.Reader) error {
+	c <- key
+	return nil
+}
+
+func TestSupersedeCancelsCompilation(t *testing.T) {
+	a, _ := newTestApp(t)
+	markers := make(cancelMarkers, 1)
+	a.cancels = markers
+
+	a.supersede("s", "1")
+	a.active.queue("s", "1")
+	a.supersede("s", "2")
+	select {
+	case key := <-markers:
+		if key != events.CancelKey("1") {
+			t.Errorf("marked %q, want %q", key, events.CancelKey("1"))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the compilation of the superseded job was not canceled")
+	}
+}
@@ -1,36 +1,85 @@
 package api
 
 import (
+	"context"
 	"log/slog"
+	"manimatic/internal/api/conversation"
 	"manimatic/internal/api/events"
 	"manimatic/internal/api/queue"
 	"manimatic/internal/api/session"
+	"manimatic/internal/api/usage"
 	"manimatic/internal/config"
 	"manimatic/internal/llm"
+	"manimatic/internal/store"
+	"manimatic/internal/worker/manimexec/security"
 	"net/http"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 type App struct {
-	config     *config.Config
-	logger     *slog.Logger
-	router     http.Handler
-	llmService *llm.Service
-	sm         *scs.SessionManager
-	MsgRouter  *events.MessageRouter
-	queueMgr   *queue.QueueManager
+	config        *config.Config
+	logger        *slog.Logger
+	router        http.Handler
+	llmService    *llm.Service
+	sm            *scs.SessionManager
+	MsgRouter     *events.MessageRouter
+	queueMgr      taskQueue
+	jobs          *jobTracker
+	active        *activeJobs
+	records       *jobRecords
+	reviews       *storyboardReviews
+	conversations *conversation.Store
+	usage         *usage.Tracker
+	renders       *renderCache
+	moderator     llm.Moderator // nil when moderation is disabled
+	validator     *security.Validator
+	history       store.Store // nil when the history is disabled
+	videos        Presigner   // Presigns the video keys of the history
+	cancels       CancelStore // nil when compilations can't be canceled
+	// reconnectGrace is how long the jobs of a session survive the loss of
+	// its event stream
+	reconnectGrace time.Duration
 }
 
-func New(cfg *config.Config, logger *slog.Logger, llmService *llm.Service, sqsClient *sqs.Client) *App {
+// sseReconnectGrace leaves EventSource, which retries after about three
+// seconds, time to reconnect before the session's jobs are canceled.
+const sseReconnectGrace = 15 * time.Second
+
+// taskQueue sends tasks to the workers and receives their results.
+type taskQueue interface {
+	EnqeueMsg(ctx context.Context, msg *events.Event) error
+	ReceiveSingleMessage(ctx context.Context) ([]types.Message, error)
+	DeleteMessage(ctx context.Context, msg types.Message) error
+}
+
+func New(cfg *config.Config, logger *slog.Logger, llmService *llm.Service, moderator llm.Moderator, sqsClient *sqs.Client, history store.Store, videos Presigner, cancels CancelStore, sessions scs.Store) *App {
+	if cancels == nil {
+		logger.Warn("no cancel store, superseded compilations run to completion")
+	}
 	app := &App{
-		config:     cfg,
-		logger:     logger,
-		llmService: llmService,
-		sm:         session.New(),
-		MsgRouter:  events.NewMessageRouter(logger),
-		queueMgr:   queue.New(sqsClient, cfg.AWS.TaskQueueURL, cfg.AWS.ResultQueueURL),
+		config:         cfg,
+		logger:         logger,
+		llmService:     llmService,
+		moderator:      moderator,
+		validator:      security.NewValidator(nil),
+		history:        history,
+		videos:         videos,
+		cancels:        cancels,
+		sm:             session.New(cfg.Session, sessions),
+		MsgRouter:      events.NewMessageRouter(logger),
+		queueMgr:       queue.New(sqsClient, cfg.AWS.TaskQueueURL, cfg.AWS.ResultQueueURL),
+		jobs:           newJobTracker(),
+		active:         newActiveJobs(),
+		records:        newJobRecords(),
+		reviews:        newStoryboardReviews(),
+		conversations:  conversation.New(2*cfg.Processing.ConversationTurns, cfg.Session.Lifetime),
+		usage:          usage.New(cfg.LLM.Prices, cfg.LLM.SessionBudget, cfg.LLM.DailyBudget, cfg.Session.Lifetime),
+		renders:        newRenderCache(cfg.Processing.VideoCacheTTL),
+		reconnectGrace: sseReconnectGrace,
 	}
 
 	h := app.setupRoutes()
@@ -0,0 +1,107 @@
+package conversation
+
+import (
+	"manimatic/internal/llm"
+	"sync"
+	"time"
+)
+
+type conversation struct {
+	messages []llm.Message
+	script   string // Last script produced by the model
+	lastUsed time.Time
+}
+
+// Store keeps a bounded conversation history per session so follow-up
+// requests can edit the existing scene.
+type Store struct {
+	mu            sync.Mutex
+	conversations map[string]*conversation
+	maxMessages   int
+	idleTTL       time.Duration
+}
+
+// New returns a store keeping up to maxMessages per session and forgetting
+// sessions that have not been used for sessionLifetime.
+func New(maxMessages int, sessionLifetime time.Duration) *Store {
+	return &Store{
+		conversations: make(map[string]*conversation),
+		maxMessages:   maxMessages,
+		idleTTL:       sessionLifetime,
+	}
+}
+
+// Start replaces the history of a session with a fresh exchange.
+func (s *Store) Start(sessionID, prompt string, result llm.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	s.conversations[sessionID] = &conversation{
+		messages: s.trim(exchange(prompt, result)),
+		script:   result.Code,
+		lastUsed: time.Now(),
+	}
+}
+
+// Append records a follow-up exchange, dropping the oldest turns once the
+// history exceeds its bound.
+func (s *Store) Append(sessionID, instruction string, result llm.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	c, ok := s.conversations[sessionID]
+	if !ok {
+		c = &conversation{}
+		s.conversations[sessionID] = c
+	}
+	c.messages = s.trim(append(c.messages, exchange(instruction, result)...))
+	c.script = result.Code
+	c.lastUsed = time.Now()
+}
+
+// History returns a copy of the session's prior turns and the last script the
+// model produced.
+func (s *Store) History(sessionID string) ([]llm.Message, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.conversations[sessionID]
+	if !ok {
+		return nil, ""
+	}
+	c.lastUsed = time.Now()
+	return append([]llm.Message(nil), c.messages...), c.script
+}
+
+// exchange records the assistant turn as the description rather than the
+// script; the current script is always sent separately.
+func exchange(prompt string, result llm.Response) []llm.Message {
+	return []llm.Message{
+		{Role: llm.RoleUser, Content: prompt},
+		{Role: llm.RoleAssistant, Content: result.Description},
+	}
+}
+
+// trim keeps the most recent messages, always dropping whole exchanges so the
+// history still starts with a user turn. A bound of zero keeps no history.
+func (s *Store) trim(msgs []llm.Message) []llm.Message {
+	if len(msgs) <= s.maxMessages {
+		return msgs
+	}
+	drop := len(msgs) - s.maxMessages
+	if drop%2 != 0 {
+		drop++
+	}
+	return append([]llm.Message(nil), msgs[drop:]...)
+}
+
+func (s *Store) prune() {
+	now := time.Now()
+	for id, c := range s.conversations {
+		if now.Sub(c.lastUsed) > s.idleTTL {
+			delete(s.conversations, id)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package conversation
+
+import (
+	"manimatic/internal/llm"
+	"testing"
+	"time"
+)
+
+func TestHistoryIsBounded(t *testing.T) {
+	s := New(4, time.Hour)
+	s.Start("s1", "draw a circle", llm.Response{Code: "v1", Description: "a circle"})
+	s.Append("s1", "make it blue", llm.Response{Code: "v2", Description: "a blue circle"})
+	s.Append("s1", "slow it down", llm.Response{Code: "v3", Description: "a slow blue circle"})
+
+	history, script := s.History("s1")
+	if script != "v3" {
+		t.Errorf("expected last script v3, got %s", script)
+	}
+	if len(history) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(history))
+	}
+	if history[0].Role != llm.RoleUser || history[0].Content != "make it blue" {
+		t.Errorf("expected history to start with the oldest kept user turn, got %+v", history[0])
+	}
+
+	// A new generation starts a fresh conversation
+	s.Start("s1", "draw a square", llm.Response{Code: "sq"})
+	if history, _ := s.History("s1"); len(history) != 2 {
+		t.Errorf("expected history to be reset, got %d messages", len(history))
+	}
+}
+
+func TestNoHistory(t *testing.T) {
+	s := New(0, time.Hour)
+	s.Start("s1", "draw a circle", llm.Response{Code: "v1"})
+
+	history, script := s.History("s1")
+	if len(history) != 0 || script != "v1" {
+		t.Errorf("expected only the script to be kept, got %d messages and %q", len(history), script)
+	}
+	if history, script := s.History("unknown"); history != nil || script != "" {
+		t.Errorf("expected nothing for an unknown session")
+	}
+}
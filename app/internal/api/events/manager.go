@@ -4,13 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 )
 
 type Event struct {
-	Kind      string `json:"kind"`       // What type of event this is
-	SessionID string `json:"session_id"` // Session this event belongs to
-	Data      any    `json:"data"`       // The event payload
+	Kind      string `json:"kind"`             // What type of event this is
+	SessionID string `json:"session_id"`       // Session this event belongs to
+	JobID     string `json:"job_id,omitempty"` // Job this event belongs to, propagated by the worker
+	Data      any    `json:"data"`             // The event payload
+}
+
+// WithJobID returns a copy of the event tagged with the given job ID
+func (e Event) WithJobID(jobID string) Event {
+	e.JobID = jobID
+	return e
 }
 
 // All possible event kinds
@@ -21,16 +29,31 @@ const (
 	KindCompileFailed     = "compile_failed"     // Compilation failed
 	KindGenerateSucceeded = "generate_succeeded" // Script generation succeeded
 	KindGenerateFailed    = "generate_failed"
+	KindGenerateProgress  = "generate_progress" // Partial script while generation is streaming
+	KindRepairAttempt     = "repair_attempt"    // A failed script is being sent back to the model for repair
+	KindStoryboardReady   = "storyboard_ready"  // Storyboard planned before the script is generated
 )
 
 // CompileRequest represents a request to compile a script
 type CompileRequest struct {
-	Script string `json:"script"`
+	Script    string          `json:"script"`
+	Narration []NarrationLine `json:"narration,omitempty"` // Turned into subtitles and, if enabled, audio
+}
+
+// NarrationLine is a line of narration read from Start to End, in seconds
+type NarrationLine struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
 }
 
 // CompileSuccess represents successful compilation
 type CompileSuccess struct {
-	VideoURL string `json:"video_url"`
+	VideoURL     string `json:"video_url"`
+	VideoKey     string `json:"video_key,omitempty"`     // S3 key of the video, to presign it again later
+	SubtitlesURL string `json:"subtitles_url,omitempty"` // Set when the script came with narration
+	SubtitlesKey string `json:"subtitles_key,omitempty"`
+	HasAudio     bool   `json:"has_audio"` // The narration was synthesized into the video
 }
 
 // CompileError represents a compilation failure
@@ -41,28 +64,74 @@ type CompileError struct {
 	Line    int    `json:"line,omitempty"` // Line number where error occurred (if available)
 }
 
-type GenerateSuccess = CompileRequest
+// GenerateSuccess carries a generated script and what produced it
+type GenerateSuccess struct {
+	Script        string `json:"script"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"` // System prompt template ID, e.g. "manim@v2"
+}
+
+// GenerateProgress carries the partial script generated so far
+type GenerateProgress struct {
+	Code string `json:"code"`
+}
+
+// RepairAttempt reports an automatic attempt to fix a script that failed to compile
+type RepairAttempt struct {
+	Attempt     int    `json:"attempt"`
+	MaxAttempts int    `json:"max_attempts"`
+	Error       string `json:"error"` // The compile error being repaired
+}
+
+// StoryboardReady carries the storyboard of a job in storyboard mode. If
+// Review is set, the script is only generated once the storyboard is
+// submitted back, possibly edited.
+type StoryboardReady struct {
+	Storyboard json.RawMessage `json:"storyboard"`
+	Model      string          `json:"model"`
+	Review     bool            `json:"review"`
+}
 
 type GenerateError struct {
 	Message string `json:"message"`           // User-friendly error message
 	Details string `json:"details,omitempty"` // Optional additional context
 	Model   string `json:"model"`             // Which model failed (e.g. "gpt-4", "claude", etc)
+	Reason  string `json:"reason,omitempty"`  // Set when generation was refused before calling the model
+
+	Categories []string `json:"categories,omitempty"` // Moderation categories the prompt was flagged for
+}
+
+// Reasons for refusing a generation
+const (
+	ReasonSessionBudget = "session_budget_exhausted"
+	ReasonDailyBudget   = "daily_budget_exhausted"
+	ReasonModeration    = "content_flagged"
+)
+
+// CancelPrefix is the storage prefix of the keys written by CancelKey.
+const CancelPrefix = "canceled_jobs/"
+
+// CancelKey is the storage key that marks the compilation of jobID as
+// canceled. The API writes it; every worker checks it before and while
+// compiling the job, whichever of them received the compile request.
+func CancelKey(jobID string) string {
+	return CancelPrefix + jobID
 }
 
 // Helper functions to create events
-func NewCompileRequest(sessionID, script string) Event {
+func NewCompileRequest(sessionID, script string, narration []NarrationLine) Event {
 	return Event{
 		Kind:      KindCompileRequested,
 		SessionID: sessionID,
-		Data:      CompileRequest{Script: script},
+		Data:      CompileRequest{Script: script, Narration: narration},
 	}
 }
 
-func NewCompileSuccess(sessionID, videoURL string) Event {
+func NewCompileSuccess(sessionID string, video CompileSuccess) Event {
 	return Event{
 		Kind:      KindCompileSucceeded,
 		SessionID: sessionID,
-		Data:      CompileSuccess{VideoURL: videoURL},
+		Data:      video,
 	}
 }
 
@@ -78,12 +147,46 @@ func NewCompileError(sessionID, message string, stdout, stderr string, line int)
 		},
 	}
 }
-func NewGenerateSuccess(sessionID, script string) Event {
+func NewGenerateSuccess(sessionID, script, model, promptVersion string) Event {
 	return Event{
 		Kind:      KindGenerateSucceeded,
 		SessionID: sessionID,
 		Data: GenerateSuccess{
-			Script: script,
+			Script:        script,
+			Model:         model,
+			PromptVersion: promptVersion,
+		},
+	}
+}
+
+func NewGenerateProgress(sessionID, code string) Event {
+	return Event{
+		Kind:      KindGenerateProgress,
+		SessionID: sessionID,
+		Data:      GenerateProgress{Code: code},
+	}
+}
+
+func NewRepairAttempt(sessionID string, attempt, maxAttempts int, compileErr string) Event {
+	return Event{
+		Kind:      KindRepairAttempt,
+		SessionID: sessionID,
+		Data: RepairAttempt{
+			Attempt:     attempt,
+			MaxAttempts: maxAttempts,
+			Error:       compileErr,
+		},
+	}
+}
+
+func NewStoryboardReady(sessionID string, storyboard json.RawMessage, model string, review bool) Event {
+	return Event{
+		Kind:      KindStoryboardReady,
+		SessionID: sessionID,
+		Data: StoryboardReady{
+			Storyboard: storyboard,
+			Model:      model,
+			Review:     review,
 		},
 	}
 }
@@ -100,9 +203,39 @@ func NewGenerateError(sessionID, message, details, model string) Event {
 	}
 }
 
+// NewGenerateRefused reports a generation that was not attempted, reason
+// being one of the Reason constants.
+func NewGenerateRefused(sessionID, message, reason, model string) Event {
+	return Event{
+		Kind:      KindGenerateFailed,
+		SessionID: sessionID,
+		Data: GenerateError{
+			Message: message,
+			Model:   model,
+			Reason:  reason,
+		},
+	}
+}
+
+// NewGenerateFlagged reports a prompt rejected by moderation.
+func NewGenerateFlagged(sessionID, model string, categories []string) Event {
+	return Event{
+		Kind:      KindGenerateFailed,
+		SessionID: sessionID,
+		Data: GenerateError{
+			Message:    "the request was flagged by content moderation",
+			Details:    "flagged categories: " + strings.Join(categories, ", "),
+			Model:      model,
+			Reason:     ReasonModeration,
+			Categories: categories,
+		},
+	}
+}
+
 type rawEvent struct {
 	Kind      string          `json:"kind"`
 	SessionID string          `json:"session_id"`
+	JobID     string          `json:"job_id"`
 	Data      json.RawMessage `json:"data"`
 }
 
@@ -114,6 +247,7 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 
 	e.Kind = raw.Kind
 	e.SessionID = raw.SessionID
+	e.JobID = raw.JobID
 
 	var err error
 	switch raw.Kind {
@@ -142,6 +276,21 @@ func (e *Event) UnmarshalJSON(data []byte) error {
 		err = json.Unmarshal(raw.Data, &d)
 		e.Data = d
 
+	case KindGenerateProgress:
+		var d GenerateProgress
+		err = json.Unmarshal(raw.Data, &d)
+		e.Data = d
+
+	case KindRepairAttempt:
+		var d RepairAttempt
+		err = json.Unmarshal(raw.Data, &d)
+		e.Data = d
+
+	case KindStoryboardReady:
+		var d StoryboardReady
+		err = json.Unmarshal(raw.Data, &d)
+		e.Data = d
+
 	default:
 		return fmt.Errorf("unknown event kind: %s", raw.Kind)
 	}
@@ -181,12 +330,23 @@ func (mr *MessageRouter) AddClient(sessionID string) (<-chan Event, func()) {
 		mr.mu.Lock()
 		defer mr.mu.Unlock()
 		close(messageChan)
-		delete(mr.clients, sessionID)
+		// The client may have reconnected before this connection was cleaned up
+		if mr.clients[sessionID] == messageChan {
+			delete(mr.clients, sessionID)
+		}
 		mr.log.Debug("Removed an SSE client", "session_id", sessionID)
 
 	}
 }
 
+// Connected reports whether the session has an SSE client.
+func (mr *MessageRouter) Connected(sessionID string) bool {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	_, ok := mr.clients[sessionID]
+	return ok
+}
+
 func (mr *MessageRouter) SendMessage(msg Event) error {
 	mr.mu.RLock()
 	defer mr.mu.RUnlock()
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"manimatic/internal/llm"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
@@ -53,29 +54,11 @@ func (s *LLMManimService) runModeration(ctx context.Context, prompt string) (boo
 	return true, nil
 }
 
-const systemPrompt = `
-You are an assistant that generates Manim code based on a user prompt.
-You MUST return exactly one JSON object that conforms to the given JSON schema:
-- code: The full Python Manim script if valid_input is true; empty string if not valid.
-- description: A brief explanation of what the script does (or why it's invalid).
-- warnings: Any warnings, assumptions, or reasons for invalidity.
-- scene_name: The primary scene class name if valid; otherwise empty if invalid.
-- valid_input: True if the user's prompt can be turned into a Manim animation; false if unrelated or disallowed.
-
-No additional text outside the JSON. No markdown formatting.
-If the user's request is unrelated to Manim or not actionable, set valid_input to false, provide a helpful description and possibly warnings, and leave code empty.
-If valid_input is true, the code should have:
-- A docstring at the top.
-- Necessary imports from manim.
-- A Scene class with a construct method implementing the animation.
-- Comments explaining key steps in the code.
-`
-
 func (s *LLMManimService) generateManimResponse(ctx context.Context, prompt string) (ManimScriptResponse, error) {
 
 	chatCompletion, err := s.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
+			openai.SystemMessage(llm.DefaultSystemPrompt),
 			openai.UserMessage(prompt),
 		}),
 		ResponseFormat: openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
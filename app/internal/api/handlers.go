@@ -3,18 +3,38 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"manimatic/internal/api/events"
 	"manimatic/internal/api/middleware"
+	"manimatic/internal/api/usage"
 	"manimatic/internal/llm"
 	"net/http"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type GenerateRequest struct {
-	Prompt string `json:"prompt"`
-	Model  string `json:"model"`
+	Prompt     string `json:"prompt"`
+	Model      string `json:"model"`
+	Candidates int    `json:"candidates"` // Scripts generated and ranked before the best is compiled, 1 if unset
+	Mode       string `json:"mode"`       // "storyboard" plans the scene before writing the script, "direct" if unset
+	Review     bool   `json:"review"`     // In storyboard mode, wait for the storyboard to be submitted back
+	Narration  bool   `json:"narration"`  // Ask for a narration track, rendered as subtitles and optionally audio
+	llm.Params        // Optional temperature, max_tokens and seed overrides
+
+	Images []llm.Image `json:"-"` // Attached through a multipart upload
+}
+type RefineRequest struct {
+	Instruction string `json:"instruction"`
+	Script      string `json:"script"` // Current editor content, defaults to the last generated script
+	Model       string `json:"model"`
+	llm.Params
+}
+type StoryboardRequest struct {
+	Storyboard llm.Storyboard `json:"storyboard"` // The reviewed storyboard, possibly edited
 }
 type CompileRequest struct {
 	Script string `json:"script"`
@@ -23,9 +43,117 @@ type CompileRequest struct {
 func (a *App) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 	var req GenerateRequest
 
+	if err := readGenerateRequest(w, r, &req); err != nil {
+		a.badRequestResponse(w, err.Error())
+		return
+	}
+	if len(req.Prompt) < 8 {
+		a.badRequestResponse(w, "invalid request body")
+		return
+	}
+	if len(req.Images) > 0 && !a.llmService.SupportsVision(req.Model) {
+		a.badRequestResponse(w, fmt.Sprintf("%s does not accept images, choose a model with vision support", a.requestedModel(req.Model)))
+		return
+	}
+
+	if err := a.llmService.ValidateParams(req.Model, req.Params); err != nil {
+		a.badRequestResponse(w, err.Error())
+		return
+	}
+	switch req.Mode {
+	case "", modeDirect:
+	case modeStoryboard:
+		if !a.llmService.SupportsStoryboard(req.Model) {
+			a.badRequestResponse(w, fmt.Sprintf("%s does not support the storyboard mode", a.requestedModel(req.Model)))
+			return
+		}
+	default:
+		a.badRequestResponse(w, fmt.Sprintf("unknown mode %q", req.Mode))
+		return
+	}
+
+	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
+	if sessionID == "" {
+		a.serverError(w, fmt.Errorf("invalid, missing or expired session"))
+		return
+	}
+
+	jobID := uuid.NewString()
+	job := a.records.create(sessionID, jobID, jobGenerate, req.Prompt)
+	a.accepted(w, job)
+	a.remember(job)
+
+	if !a.allowGeneration(sessionID, jobID, req.Model) {
+		return
+	}
+
+	jobCtx := a.supersede(sessionID, jobID)
+	go func() {
+		a.records.setStatus(jobID, JobGenerating)
+		ctx, cancel := context.WithTimeout(jobCtx, a.config.LLM.RequestTimeout)
+		defer cancel()
+		if !a.moderate(ctx, sessionID, jobID, req.Prompt, req.Model) {
+			return
+		}
+		var board *llm.Storyboard
+		if req.Mode == modeStoryboard {
+			planned, ok := a.planStoryboard(ctx, sessionID, jobID, req)
+			if !ok {
+				return
+			}
+			board = &planned
+		}
+		a.generateScript(ctx, sessionID, jobID, req, board)
+	}()
+
+}
+
+// generateScript generates, validates and dispatches the script of req. With
+// a storyboard the script is written to follow it.
+func (a *App) generateScript(ctx context.Context, sessionID, jobID string, req GenerateRequest, board *llm.Storyboard) {
+	prompt := req.Prompt
+	if board != nil {
+		req.Prompt = llm.StoryboardPrompt(prompt, *board)
+	}
+	if req.Narration {
+		req.Prompt = llm.NarrationPrompt(req.Prompt)
+	}
+	result, err := a.generate(ctx, sessionID, jobID, req)
+	if err != nil {
+		if a.active.current(sessionID, jobID) {
+			a.logger.Error("failed to generate script", "error", err)
+		}
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to generate script", err.Error(), llm.FailedModel(err, a.requestedModel(req.Model))))
+		return
+	}
+	a.recordUsage(sessionID, result)
+	if !result.ValidInput || result.Code == "" {
+		a.logger.Info("generated script flagged as invalid or empty", "prompt", prompt)
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to generate scene for the given prompt", result.Warnings, result.Model))
+		return
+	}
+	result, err = a.validateGenerated(ctx, sessionID, req.Prompt, result)
+	if err != nil {
+		a.logger.Error("generated script failed validation", "session_id", sessionID, "error", err)
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "the generated script failed validation", err.Error(), result.Model))
+		return
+	}
+
+	a.conversations.Start(sessionID, prompt, result)
+	a.dispatchScript(sessionID, jobID, req.Prompt, result.Model, result)
+}
+
+func (a *App) HandleRefine(w http.ResponseWriter, r *http.Request) {
+	var req RefineRequest
+
 	err := ReadJSON(w, r, &req)
-	if err != nil || len(req.Prompt) < 8 {
+	if err != nil || len(req.Instruction) < 3 {
 		a.badRequestResponse(w, "invalid request body")
+		return
+	}
+	if err := a.llmService.ValidateParams(req.Model, req.Params); err != nil {
+		a.badRequestResponse(w, err.Error())
+		return
 	}
 
 	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
@@ -34,40 +162,226 @@ func (a *App) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	history, lastScript := a.conversations.History(sessionID)
+	script := req.Script
+	if script == "" {
+		script = lastScript
+	}
+	if script == "" {
+		a.badRequestResponse(w, "nothing to refine, generate a scene first")
+		return
+	}
+
+	jobID := uuid.NewString()
+	job := a.records.create(sessionID, jobID, jobRefine, req.Instruction)
+	a.accepted(w, job)
+	a.remember(job)
+
+	if !a.allowGeneration(sessionID, jobID, req.Model) {
+		return
+	}
 
+	jobCtx := a.supersede(sessionID, jobID)
 	go func() {
-		ctx := context.Background()
-		result, err := a.llmService.Generate(ctx, req.Prompt, req.Model)
-		var msg events.Event
+		a.records.setStatus(jobID, JobGenerating)
+		ctx, cancel := context.WithTimeout(jobCtx, a.config.LLM.RequestTimeout)
+		defer cancel()
+		if !a.moderate(ctx, sessionID, jobID, req.Instruction, req.Model) {
+			return
+		}
+		result, err := a.llmService.Refine(ctx, llm.RefineRequest{
+			History:     history,
+			Script:      script,
+			Instruction: req.Instruction,
+			Params:      req.Params,
+		}, req.Model)
 		if err != nil {
-			a.logger.Error("failed to generate script", "error", err)
-			msg = events.NewGenerateError(sessionID, "failed to generate script", err.Error(), "openai-4o")
-			_ = a.MsgRouter.SendMessage(msg)
+			if jobCtx.Err() == nil {
+				a.logger.Error("failed to refine script", "error", err)
+			}
+			a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to refine script", err.Error(), llm.FailedModel(err, a.requestedModel(req.Model))))
 			return
 		}
+		a.recordUsage(sessionID, result)
 		if !result.ValidInput || result.Code == "" {
-			a.logger.Info("generated script flagged as invalid or empty", "prompt", req.Prompt)
-			msg = events.NewGenerateError(sessionID, "failed to generate scene for the given prompt", result.Warnings, "openai-4o")
-			_ = a.MsgRouter.SendMessage(msg)
+			a.logger.Info("refined script flagged as invalid or empty", "instruction", req.Instruction)
+			a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to apply the requested change", result.Warnings, result.Model))
+			return
+		}
+		result, err = a.validateGenerated(ctx, sessionID, req.Instruction, result)
+		if err != nil {
+			a.logger.Error("refined script failed validation", "session_id", sessionID, "error", err)
+			a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "the refined script failed validation", err.Error(), result.Model))
 			return
 		}
 
-		clientUpdate := events.NewGenerateSuccess(sessionID, result.Code)
-		workerTask := events.NewCompileRequest(sessionID, result.Code)
-		a.logger.Info("generated manim script", "session_id", sessionID)
-		go func() {
-			err := a.queueMgr.EnqeueMsg(context.TODO(), &workerTask)
-			if err != nil {
-				slog.Error("failed to enqueue message", "error", err, "message", msg)
-			}
-		}()
-		err = a.MsgRouter.SendMessage(clientUpdate)
+		a.conversations.Append(sessionID, req.Instruction, result)
+		a.dispatchScript(sessionID, jobID, req.Instruction, result.Model, result)
+	}()
+}
+
+// generate produces the script for req, streaming progress to the client
+// unless the request asked for several candidates to be ranked.
+func (a *App) generate(ctx context.Context, sessionID, jobID string, req GenerateRequest) (llm.Response, error) {
+	n := min(req.Candidates, a.config.LLM.MaxCandidates)
+	if n <= 1 {
+		return a.llmService.GenerateStream(ctx, req.Prompt, req.Images, req.Model, req.Params, a.progressReporter(sessionID, jobID))
+	}
+
+	result, candidates, err := a.llmService.GenerateCandidates(ctx, req.Prompt, req.Images, req.Model, req.Params, n)
+	if err != nil {
+		return result, err
+	}
+	for i, c := range candidates {
+		a.logger.Info("ranked candidate", "session_id", sessionID, "rank", i+1, "model", c.Model, "score", c.Score, "problems", c.Problems)
+	}
+	return result, nil
+}
+
+// dispatchScript sends a generated script to the client and queues it for
+// compilation, remembering its origin when the repair loop is enabled. The
+// script passed validation, so later identical prompts may be served it. A
+// script that was rendered recently is not compiled again, and one whose job
+// was superseded meanwhile is not compiled at all.
+func (a *App) dispatchScript(sessionID, jobID, prompt, model string, result llm.Response) {
+	a.llmService.Remember(context.Background(), result)
+	clientUpdate := events.NewGenerateSuccess(sessionID, result.Code, model, result.Prompt).WithJobID(jobID)
+	narration := narrationTrack(result.Narration)
+	compileReq := events.CompileRequest{Script: result.Code, Narration: narration}
+
+	if video, ok := a.cachedVideo(context.Background(), sessionID, compileReq); ok {
+		a.logger.Info("reusing rendered video", "session_id", sessionID, "job_id", jobID, "model", model, "prompt_version", result.Prompt, "cached", result.Cached)
+		if a.notify(sessionID, jobID, clientUpdate) {
+			a.notify(sessionID, jobID, events.NewCompileSuccess(sessionID, video))
+			a.active.finish(sessionID, jobID)
+		}
+		return
+	}
+
+	if !a.active.queue(sessionID, jobID) {
+		a.logger.Info("dropping script of superseded job", "session_id", sessionID, "job_id", jobID)
+		return
+	}
+	a.records.setStatus(jobID, JobCompiling)
+
+	a.renders.expect(sessionID, jobID, compileReq)
+	if a.config.Processing.MaxRepairAttempts > 0 {
+		a.jobs.put(jobID, generationJob{
+			sessionID: sessionID,
+			prompt:    prompt,
+			model:     model,
+			script:    result.Code,
+			narration: narration,
+			createdAt: time.Now(),
+		})
+	}
+
+	workerTask := events.NewCompileRequest(sessionID, result.Code, narration).WithJobID(jobID)
+	a.logger.Info("generated manim script", "session_id", sessionID, "job_id", jobID, "model", model, "prompt_version", result.Prompt, "cached", result.Cached)
+	go func() {
+		err := a.queueMgr.EnqeueMsg(context.TODO(), &workerTask)
 		if err != nil {
-			a.logger.Error("failed to send message to client channel", "session_id", sessionID, "error", err)
+			slog.Error("failed to enqueue message", "error", err, "message", workerTask)
 		}
 	}()
+	a.notify(sessionID, jobID, clientUpdate)
+}
+
+// narrationTrack converts the narration of a response for the worker.
+func narrationTrack(lines []llm.NarrationLine) []events.NarrationLine {
+	if len(lines) == 0 {
+		return nil
+	}
+	track := make([]events.NarrationLine, len(lines))
+	for i, l := range lines {
+		track[i] = events.NarrationLine{Start: l.Start, End: l.End, Text: l.Text}
+	}
+	return track
+}
+
+// allowGeneration checks the session and daily budgets, failing jobID with
+// the reason when one of them is spent.
+func (a *App) allowGeneration(sessionID, jobID, model string) bool {
+	err := a.usage.Allow(sessionID)
+	if err == nil {
+		return true
+	}
+
+	reason, message := events.ReasonSessionBudget, "this session has used up its generation budget"
+	if errors.Is(err, usage.ErrDailyBudget) {
+		reason, message = events.ReasonDailyBudget, "the daily generation budget has been used up, try again tomorrow"
+	}
+	a.logger.Warn("generation refused", "session_id", sessionID, "reason", reason)
+	a.publish(events.NewGenerateRefused(sessionID, message, reason, a.requestedModel(model)).WithJobID(jobID))
+	return false
+}
+
+// moderate checks user input when moderation is enabled, telling the client
+// why the request was rejected.
+func (a *App) moderate(ctx context.Context, sessionID, jobID, text, model string) bool {
+	if a.moderator == nil {
+		return true
+	}
+
+	verdict, err := a.moderator.Moderate(ctx, text)
+	if err != nil {
+		a.logger.Error("moderation failed", "session_id", sessionID, "error", err)
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to moderate the request", err.Error(), a.requestedModel(model)))
+		return false
+	}
+	if !verdict.Flagged {
+		return true
+	}
+
+	a.logger.Info("prompt flagged by moderation", "session_id", sessionID, "categories", verdict.Categories)
+	a.failJob(sessionID, jobID, events.NewGenerateFlagged(sessionID, a.requestedModel(model), verdict.Categories))
+	return false
+}
+
+// recordUsage accounts the tokens spent producing result to the session
+func (a *App) recordUsage(sessionID string, result llm.Response) {
+	cost := a.usage.Record(sessionID, result.Model, result.Usage)
+	a.logger.Info("llm usage",
+		"session_id", sessionID,
+		"model", result.Model,
+		"prompt_tokens", result.Usage.PromptTokens,
+		"completion_tokens", result.Usage.CompletionTokens,
+		"cost_usd", cost,
+		"cached", result.Cached,
+		"session_cost_usd", a.usage.Session(sessionID).Cost,
+		"daily_cost_usd", a.usage.Today().Cost,
+	)
+}
 
+// requestedModel resolves the model a request asked for
+func (a *App) requestedModel(model string) string {
+	if model == "" {
+		return a.llmService.DefaultModel()
+	}
+	return model
+}
+
+// progressInterval limits how often partial scripts are pushed to the client
+// so that progress updates don't crowd out the final result in the SSE channel.
+const progressInterval = 150 * time.Millisecond
+
+func (a *App) progressReporter(sessionID, jobID string) func(llm.Chunk) {
+	var lastSent time.Time
+	var lastCode string
+	return func(chunk llm.Chunk) {
+		if time.Since(lastSent) < progressInterval || !a.active.current(sessionID, jobID) {
+			return
+		}
+		code := llm.PartialCode(chunk.Content)
+		if code == "" || code == lastCode {
+			return
+		}
+		lastSent = time.Now()
+		lastCode = code
+		if err := a.MsgRouter.SendMessage(events.NewGenerateProgress(sessionID, code).WithJobID(jobID)); err != nil {
+			a.logger.Debug("failed to send generation progress", "session_id", sessionID, "error", err)
+		}
+	}
 }
 
 func (a *App) handleCompile(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +390,7 @@ func (a *App) handleCompile(w http.ResponseWriter, r *http.Request) {
 	err := ReadJSON(w, r, &req)
 	if err != nil || len(req.Script) < 8 {
 		a.badRequestResponse(w, "invalid request body")
+		return
 	}
 
 	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
@@ -84,9 +399,20 @@ func (a *App) handleCompile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	jobID := uuid.NewString()
+	job := a.records.create(sessionID, jobID, jobCompile, "")
+	a.supersede(sessionID, jobID)
+	a.active.queue(sessionID, jobID)
+	a.records.update(jobID, func(j *Job) {
+		j.Status = JobCompiling
+		j.Script = req.Script
+	})
+	job.Status = JobCompiling
+	job.Script = req.Script
+	a.accepted(w, job)
+	a.remember(job)
 	go func() {
-		msg := events.NewCompileRequest(sessionID, req.Script)
+		msg := events.NewCompileRequest(sessionID, req.Script, nil).WithJobID(jobID)
 		err := a.queueMgr.EnqeueMsg(context.TODO(), &msg)
 		if err != nil {
 			slog.Error("failed to enqueue message", "error", err, "message", msg)
@@ -94,6 +420,22 @@ func (a *App) handleCompile(w http.ResponseWriter, r *http.Request) {
 	}()
 }
 
+// disconnected cancels the current job of a session whose event stream
+// closed, since nobody is left to receive its results. EventSource reconnects
+// on its own, so the job is kept if the client is back within the grace
+// period.
+func (a *App) disconnected(sessionID string) {
+	time.AfterFunc(a.reconnectGrace, func() {
+		if a.MsgRouter.Connected(sessionID) {
+			return
+		}
+		if jobID := a.active.stop(sessionID); jobID != "" {
+			a.cancelCompile(sessionID, jobID)
+		}
+		a.remember(a.records.cancel(sessionID, "")...)
+	})
+}
+
 func (a *App) sseHandler(w http.ResponseWriter, r *http.Request) {
 	id := a.sm.GetString(r.Context(), string(middleware.UserSessionTokenKey))
 	if id == "" {
@@ -116,6 +458,7 @@ func (a *App) sseHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	messageChan, cleanup := a.MsgRouter.AddClient(id)
+	defer a.disconnected(id)
 	defer cleanup()
 
 	// Event loop
@@ -158,6 +501,8 @@ func (a *App) featuresHandler(w http.ResponseWriter, r *http.Request) {
 func (a *App) modelsHandler(w http.ResponseWriter, _ *http.Request) {
 	response := llm.ModelsResponse{
 		Models:       a.llmService.AvailableModels(),
+		Details:      a.llmService.Models(),
+		Unavailable:  a.llmService.UnavailableModels(),
 		DefaultModel: a.llmService.DefaultModel(),
 	}
 	WriteJSON(w, http.StatusOK, response)
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"manimatic/internal/api/events"
+	"manimatic/internal/api/features"
+	"manimatic/internal/config"
+	"manimatic/internal/llm"
+	"manimatic/internal/llm/fake"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeQueue hands the tasks sent to the workers to the test and the results
+// the test delivers to the app.
+type fakeQueue struct {
+	tasks   chan events.Event
+	results chan events.Event
+}
+
+func (q *fakeQueue) EnqeueMsg(_ context.Context, msg *events.Event) error {
+	q.tasks <- *msg
+	return nil
+}
+
+func (q *fakeQueue) ReceiveSingleMessage(ctx context.Context) ([]types.Message, error) {
+	select {
+	case ev := <-q.results:
+		body, err := json.Marshal(ev)
+		if err != nil {
+			return nil, err
+		}
+		return []types.Message{{Body: aws.String(string(body))}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *fakeQueue) DeleteMessage(context.Context, types.Message) error { return nil }
+
+const circleScript = `from manim import *
+
+class Circle(Scene):
+    def construct(self):
+        self.play(Create(Circle()))
+`
+
+// rejectedScript is refused by the validator before it reaches the queue.
+const rejectedScript = `import os
+from manim import *
+
+class Circle(Scene):
+    def construct(self):
+        os.remove("video.mp4")
+`
+
+// newTestApp returns an app generating with a fake model answering from
+// fixtures and queueing compile requests to the returned queue.
+func newTestApp(t *testing.T, fixtures ...fake.Fixture) (*App, *fakeQueue) {
+	t.Helper()
+	llmService := llm.NewService("fake")
+	llmService.RegisterProvider(fake.New("fake", fixtures...))
+	cfg := &config.Config{}
+	cfg.LLM.RequestTimeout = time.Minute
+	cfg.Processing.Features = features.New("")
+	a := New(cfg, slog.Default(), llmService, nil, nil, nil, nil, nil, nil)
+	queue := &fakeQueue{tasks: make(chan events.Event, 10), results: make(chan events.Event, 1)}
+	a.queueMgr = queue
+	return a, queue
+}
+
+// postGenerate starts a generate job.
+func postGenerate(t *testing.T, a *App, body string) Job {
+	t.Helper()
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/generate", strings.NewReader(body)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("POST /generate = %d %s, want 202", w.Code, w.Body)
+	}
+	var job Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || w.Header().Get("Location") != "/jobs/"+job.ID {
+		t.Fatalf("unexpected job %+v at %q", job, w.Header().Get("Location"))
+	}
+	return job
+}
+
+// nextTask waits for the next compile request sent to the workers.
+func nextTask(t *testing.T, queue *fakeQueue) (events.Event, events.CompileRequest) {
+	t.Helper()
+	select {
+	case task := <-queue.tasks:
+		req, ok := task.Data.(events.CompileRequest)
+		if task.Kind != events.KindCompileRequested || !ok {
+			t.Fatalf("unexpected task %+v", task)
+		}
+		return task, req
+	case <-time.After(5 * time.Second):
+		t.Fatal("no compile request was queued")
+	}
+	panic("unreachable")
+}
+
+// deliver processes ev as a result sent back by a worker.
+func deliver(t *testing.T, a *App, queue *fakeQueue, ev events.Event) {
+	t.Helper()
+	queue.results <- ev
+	if err := a.processNextVideoUpdateMessage(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitJob waits until the record of jobID has status.
+func waitJob(t *testing.T, a *App, jobID, status string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		a.records.mu.Lock()
+		job := *a.records.jobs[jobID]
+		a.records.mu.Unlock()
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", jobID, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandleGenerate(t *testing.T) {
+	a, queue := newTestApp(t, fake.Fixture{
+		Match:    "circle",
+		Response: &llm.Response{Code: circleScript, SceneName: "Circle", ValidInput: true},
+	})
+
+	job := postGenerate(t, a, `{"prompt": "draw a circle"}`)
+	if job.Kind != jobGenerate || job.Prompt != "draw a circle" {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	task, req := nextTask(t, queue)
+	if task.JobID != job.ID || req.Script != circleScript {
+		t.Errorf("unexpected task %+v", task)
+	}
+
+	job, ok := a.records.get(task.SessionID, job.ID)
+	if !ok || job.Status != JobCompiling || job.Script != circleScript || job.Model != "fake" {
+		t.Errorf("unexpected job record %+v", job)
+	}
+}
+
+func TestGenerateCachesValidScripts(t *testing.T) {
+	a, queue := newTestApp(t,
+		fake.Fixture{Match: "circle", Times: 1, Response: &llm.Response{Code: rejectedScript, ValidInput: true}},
+		fake.Fixture{Match: "circle", Times: 1, Response: &llm.Response{Code: circleScript, ValidInput: true}},
+	)
+	a.llmService.SetCache(llm.NewMemoryCache(10, time.Hour))
+
+	rejected := postGenerate(t, a, `{"prompt": "draw a circle"}`)
+	waitJob(t, a, rejected.ID, JobFailed)
+
+	// The rejected script is not served from the cache
+	job := postGenerate(t, a, `{"prompt": "draw a circle"}`)
+	if task, req := nextTask(t, queue); task.JobID != job.ID || req.Script != circleScript {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	// The accepted one is, the model has no answer left
+	job = postGenerate(t, a, `{"prompt": "draw a circle"}`)
+	if _, req := nextTask(t, queue); req.Script != circleScript {
+		t.Fatalf("unexpected script %q", req.Script)
+	}
+	if job = waitJob(t, a, job.ID, JobCompiling); job.Model != "fake" {
+		t.Errorf("unexpected job record %+v", job)
+	}
+}
+
+func TestDisconnectGrace(t *testing.T) {
+	a := &App{logger: slog.Default(), MsgRouter: events.NewMessageRouter(slog.Default()), active: newActiveJobs(), records: newJobRecords(), jobs: newJobTracker(), reconnectGrace: 20 * time.Millisecond}
+	a.supersede("s", "1")
+	_, cleanup := a.MsgRouter.AddClient("s")
+
+	// EventSource reconnects before the grace period ends
+	cleanup()
+	a.disconnected("s")
+	_, cleanup = a.MsgRouter.AddClient("s")
+	time.Sleep(50 * time.Millisecond)
+	if !a.active.current("s", "1") {
+		t.Fatal("expected the job to survive a reconnect")
+	}
+
+	cleanup()
+	a.disconnected("s")
+	time.Sleep(50 * time.Millisecond)
+	if a.active.current("s", "1") {
+		t.Error("expected the job to be canceled once the client is gone")
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"manimatic/internal/api/middleware"
+	"manimatic/internal/store"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// History listing limits
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// videoURLTTL is how long the video links presigned by the API stay valid.
+const videoURLTTL = 15 * time.Minute
+
+// historySaveTimeout bounds a write to the history store.
+const historySaveTimeout = 5 * time.Second
+
+// Presigner makes temporary links to stored videos.
+type Presigner interface {
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// HistoryEntry is a past job of the session as returned by GET /history.
+// Its video links are presigned again on every request.
+type HistoryEntry struct {
+	ID           string    `json:"id"`
+	Kind         string    `json:"kind"`
+	Status       string    `json:"status"`
+	Prompt       string    `json:"prompt,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Script       string    `json:"script,omitempty"` // Only sent by GET /history/{id}
+	Error        string    `json:"error,omitempty"`
+	VideoURL     string    `json:"video_url,omitempty"`
+	SubtitlesURL string    `json:"subtitles_url,omitempty"`
+	HasAudio     bool      `json:"has_audio"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// HistoryPage is a page of the history of the session, newest first.
+type HistoryPage struct {
+	Entries []HistoryEntry `json:"entries"`
+	Total   int            `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
+
+// remember saves the state of jobs into the history. Failures are only
+// logged, the history is not worth failing a job for.
+func (a *App) remember(jobs ...Job) {
+	if a.history == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), historySaveTimeout)
+	defer cancel()
+	for _, job := range jobs {
+		if err := a.history.Save(ctx, historyRecord(job)); err != nil {
+			a.logger.Error("failed to save job history", "session_id", job.sessionID, "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+func historyRecord(job Job) store.Entry {
+	e := store.Entry{
+		ID:           job.ID,
+		SessionID:    job.sessionID,
+		Kind:         job.Kind,
+		Status:       job.Status,
+		Prompt:       job.Prompt,
+		Model:        job.Model,
+		Script:       job.Script,
+		VideoKey:     job.videoKey,
+		SubtitlesKey: job.subtitlesKey,
+		HasAudio:     job.HasAudio,
+		CreatedAt:    job.CreatedAt,
+		UpdatedAt:    job.UpdatedAt,
+	}
+	if job.Error != nil {
+		e.Error = job.Error.Message
+	}
+	return e
+}
+
+// historyEntry converts a stored entry for the client, presigning its keys.
+func (a *App) historyEntry(ctx context.Context, e store.Entry) HistoryEntry {
+	entry := HistoryEntry{
+		ID:        e.ID,
+		Kind:      e.Kind,
+		Status:    e.Status,
+		Prompt:    e.Prompt,
+		Model:     e.Model,
+		Script:    e.Script,
+		Error:     e.Error,
+		HasAudio:  e.HasAudio,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+	entry.VideoURL = a.presign(ctx, e.VideoKey)
+	entry.SubtitlesURL = a.presign(ctx, e.SubtitlesKey)
+	return entry
+}
+
+// presign returns a link to key, or nothing if it can't be made.
+func (a *App) presign(ctx context.Context, key string) string {
+	if key == "" || a.videos == nil {
+		return ""
+	}
+	url, err := a.videos.PresignGet(ctx, key, videoURLTTL)
+	if err != nil {
+		a.logger.Error("failed to presign history video", "key", key, "error", err)
+		return ""
+	}
+	return url
+}
+
+func (a *App) handleHistory(w http.ResponseWriter, r *http.Request) {
+	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
+	if sessionID == "" {
+		a.serverError(w, fmt.Errorf("invalid, missing or expired session"))
+		return
+	}
+
+	page := store.Page{Limit: defaultHistoryLimit}
+	var err error
+	if v := r.URL.Query().Get("limit"); v != "" {
+		page.Limit, err = strconv.Atoi(v)
+		if err != nil || page.Limit < 1 || page.Limit > maxHistoryLimit {
+			a.badRequestResponse(w, "limit must be a number between 1 and "+strconv.Itoa(maxHistoryLimit))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		page.Offset, err = strconv.Atoi(v)
+		if err != nil || page.Offset < 0 {
+			a.badRequestResponse(w, "offset must be a positive number")
+			return
+		}
+	}
+
+	records, total, err := a.history.List(r.Context(), sessionID, page)
+	if err != nil {
+		a.serverError(w, err)
+		return
+	}
+
+	resp := HistoryPage{Entries: make([]HistoryEntry, len(records)), Total: total, Limit: page.Limit, Offset: page.Offset}
+	for i, e := range records {
+		resp.Entries[i] = a.historyEntry(r.Context(), e)
+		resp.Entries[i].Script = ""
+	}
+	if err := WriteJSON(w, http.StatusOK, resp); err != nil {
+		a.logger.Error("failed to write response", "error", err)
+	}
+}
+
+func (a *App) handleHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
+	if sessionID == "" {
+		a.serverError(w, fmt.Errorf("invalid, missing or expired session"))
+		return
+	}
+
+	e, err := a.history.Get(r.Context(), sessionID, r.PathValue("id"))
+	if errors.Is(err, store.ErrNotFound) {
+		a.errorResponse(w, http.StatusNotFound, "history entry not found")
+		return
+	}
+	if err != nil {
+		a.serverError(w, err)
+		return
+	}
+	if err := WriteJSON(w, http.StatusOK, a.historyEntry(r.Context(), e)); err != nil {
+		a.logger.Error("failed to write response", "error", err)
+	}
+}
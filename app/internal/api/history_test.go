@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"manimatic/internal/api/events"
+	"manimatic/internal/store"
+	"testing"
+	"time"
+)
+
+type fakePresigner struct{}
+
+func (fakePresigner) PresignGet(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://signed/" + key, nil
+}
+
+func TestRememberJob(t *testing.T) {
+	a := &App{logger: slog.Default(), records: newJobRecords(), history: store.NewMemory(), videos: fakePresigner{}}
+	ctx := context.Background()
+
+	a.remember(a.records.create("s", "1", jobGenerate, "draw a circle"))
+	for _, ev := range []events.Event{
+		events.NewGenerateSuccess("s", "script", "fake-model", "default@v1"),
+		events.NewCompileSuccess("s", events.CompileSuccess{VideoURL: "https://video", VideoKey: "manim_outputs/s/1.mp4"}),
+	} {
+		job, _ := a.records.observe(ev.WithJobID("1"))
+		a.remember(job)
+	}
+
+	e, err := a.history.Get(ctx, "s", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := a.historyEntry(ctx, e)
+	if entry.Status != JobSucceeded || entry.Prompt != "draw a circle" || entry.Script != "script" || entry.Model != "fake-model" || entry.VideoURL != "https://signed/manim_outputs/s/1.mp4" {
+		t.Errorf("unexpected history entry %+v", entry)
+	}
+
+	a.remember(a.records.create("s", "2", jobCompile, ""))
+	a.remember(a.records.cancel("s", "")...)
+	if e, err := a.history.Get(ctx, "s", "2"); err != nil || e.Status != JobCanceled {
+		t.Errorf("canceled job saved as %+v, %v", e, err)
+	}
+}
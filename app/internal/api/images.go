@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"manimatic/internal/llm"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// Limits of images attached to a generate request.
+const (
+	maxImages     = 4
+	maxImageSize  = 5 * 1_024 * 1_024
+	maxUploadSize = maxBodySize + maxImages*maxImageSize
+	// uploadTimeout replaces the server timeouts while images are uploaded,
+	// which takes longer than reading a JSON body.
+	uploadTimeout = 2 * time.Minute
+)
+
+// imageTypes are the image formats all vision providers accept.
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// readGenerateRequest reads a generate request sent as JSON or, to attach
+// images, as a multipart form with the JSON in the "request" field and the
+// images as "image" files.
+func readGenerateRequest(w http.ResponseWriter, r *http.Request, req *GenerateRequest) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return ReadJSON(w, r, req)
+	}
+
+	// Writers without deadline support keep the server timeouts
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(uploadTimeout)
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxBodySize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("request body must not be larger than %d MB", maxUploadSize>>20)
+		}
+		return errors.New("invalid multipart form")
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if err := json.Unmarshal([]byte(r.FormValue("request")), req); err != nil {
+		return errors.New("invalid JSON provided in the request field")
+	}
+
+	files := r.MultipartForm.File["image"]
+	if len(files) > maxImages {
+		return fmt.Errorf("at most %d images can be attached", maxImages)
+	}
+	for _, fh := range files {
+		img, err := readImage(fh)
+		if err != nil {
+			return err
+		}
+		req.Images = append(req.Images, img)
+	}
+	return nil
+}
+
+// readImage reads an uploaded image, checking its size and its type as
+// sniffed from the content rather than the declared one.
+func readImage(fh *multipart.FileHeader) (llm.Image, error) {
+	tooLarge := fmt.Errorf("image %q must not be larger than %d MB", fh.Filename, maxImageSize>>20)
+	if fh.Size > maxImageSize {
+		return llm.Image{}, tooLarge
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return llm.Image{}, fmt.Errorf("failed to read image %q", fh.Filename)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxImageSize+1))
+	if err != nil {
+		return llm.Image{}, fmt.Errorf("failed to read image %q", fh.Filename)
+	}
+	if len(data) > maxImageSize {
+		return llm.Image{}, tooLarge
+	}
+
+	mediaType := http.DetectContentType(data)
+	if !imageTypes[mediaType] {
+		return llm.Image{}, fmt.Errorf("image %q is %s, only PNG, JPEG, GIF and WebP images are accepted", fh.Filename, mediaType)
+	}
+	return llm.Image{MediaType: mediaType, Data: data}, nil
+}
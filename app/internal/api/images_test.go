@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func newMultipartRequest(t *testing.T, request string, images map[string][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("request", request); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range images {
+		fw, err := mw.CreateFormFile("image", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(data)
+	}
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/generate", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestReadGenerateRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		request    string
+		images     map[string][]byte
+		wantImages int
+		wantErr    string
+	}{
+		{name: "json only", request: `{"prompt":"animate this"}`},
+		{name: "image", request: `{"prompt":"animate this"}`, images: map[string][]byte{"board.png": pngHeader}, wantImages: 1},
+		{name: "unsupported type", request: `{"prompt":"animate this"}`, images: map[string][]byte{"notes.txt": []byte("plain text")}, wantErr: "only PNG, JPEG, GIF and WebP"},
+		{name: "too large", request: `{"prompt":"animate this"}`, images: map[string][]byte{"huge.png": append(pngHeader, make([]byte, maxImageSize)...)}, wantErr: "must not be larger than 5 MB"},
+		{name: "too many", request: `{"prompt":"animate this"}`, images: map[string][]byte{"1.png": pngHeader, "2.png": pngHeader, "3.png": pngHeader, "4.png": pngHeader, "5.png": pngHeader}, wantErr: "at most 4 images"},
+		{name: "bad json", request: `{"prompt":`, wantErr: "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req GenerateRequest
+			err := readGenerateRequest(httptest.NewRecorder(), newMultipartRequest(t, tt.request, tt.images), &req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.Prompt != "animate this" || len(req.Images) != tt.wantImages {
+				t.Errorf("got prompt %q with %d images", req.Prompt, len(req.Images))
+			}
+			for _, img := range req.Images {
+				if img.MediaType != "image/png" {
+					t.Errorf("media type = %s, want image/png", img.MediaType)
+				}
+			}
+		})
+	}
+}
+
+func TestReadGenerateRequestSlowUpload(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateRequest
+		if err := readGenerateRequest(w, r, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, len(req.Images))
+	}))
+	// Much shorter than the upload takes
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// Trickle images close to the size limit
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		mw.WriteField("request", `{"prompt":"animate this"}`)
+		for i := range maxImages {
+			time.Sleep(50 * time.Millisecond)
+			fw, err := mw.CreateFormFile("image", fmt.Sprintf("%d.png", i))
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			fw.Write(append(pngHeader, make([]byte, maxImageSize-len(pngHeader))...))
+		}
+		pw.CloseWithError(mw.Close())
+	}()
+
+	resp, err := http.Post(srv.URL, mw.FormDataContentType(), pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != fmt.Sprint(maxImages) {
+		t.Errorf("got %d %q, want %d images", resp.StatusCode, body, maxImages)
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"manimatic/internal/api/events"
+	"manimatic/internal/api/middleware"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Job statuses
+const (
+	JobQueued     = "queued"     // Accepted, not started yet
+	JobGenerating = "generating" // The script or its storyboard is being generated
+	JobCompiling  = "compiling"  // The script was sent to the worker
+	JobSucceeded  = "succeeded"
+	JobFailed     = "failed"
+	JobCanceled   = "canceled" // Superseded by a newer job of the session or abandoned by the client
+)
+
+// Job kinds
+const (
+	jobGenerate = "generate"
+	jobRefine   = "refine"
+	jobCompile  = "compile"
+)
+
+// jobRecordTTL bounds how long finished jobs can be looked up.
+const jobRecordTTL = time.Hour
+
+// Job is the state of a generate, refine or compile request as returned by
+// GET /jobs/{id}. It follows the events of the job.
+type Job struct {
+	ID             string          `json:"id"`
+	Kind           string          `json:"kind"`
+	Status         string          `json:"status"`
+	Prompt         string          `json:"prompt,omitempty"`
+	Model          string          `json:"model,omitempty"`
+	Script         string          `json:"script,omitempty"`
+	Storyboard     json.RawMessage `json:"storyboard,omitempty"`
+	VideoURL       string          `json:"video_url,omitempty"`
+	SubtitlesURL   string          `json:"subtitles_url,omitempty"`
+	HasAudio       bool            `json:"has_audio"`
+	RepairAttempts int             `json:"repair_attempts,omitempty"`
+	Error          *JobError       `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+
+	sessionID    string
+	videoKey     string
+	subtitlesKey string
+}
+
+// JobError is why a job failed, taken from its generate_failed or
+// compile_failed event.
+type JobError struct {
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"` // Compiler output or model warnings
+	Line    int    `json:"line,omitempty"`
+	Reason  string `json:"reason,omitempty"` // Set when the generation was refused
+}
+
+func (j *Job) done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+type jobRecords struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func newJobRecords() *jobRecords {
+	return &jobRecords{jobs: make(map[string]*Job)}
+}
+
+// create records a new queued job of the session.
+func (r *jobRecords) create(sessionID, jobID, kind, prompt string) Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, j := range r.jobs {
+		if now.Sub(j.UpdatedAt) > jobRecordTTL {
+			delete(r.jobs, id)
+		}
+	}
+	job := &Job{ID: jobID, Kind: kind, Status: JobQueued, Prompt: prompt, CreatedAt: now, UpdatedAt: now, sessionID: sessionID}
+	r.jobs[jobID] = job
+	return *job
+}
+
+// get returns the job if it belongs to the session.
+func (r *jobRecords) get(sessionID, jobID string) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[jobID]
+	if !ok || j.sessionID != sessionID {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// update applies fn to a job that hasn't finished and returns the job as
+// updated.
+func (r *jobRecords) update(jobID string, fn func(j *Job)) (Job, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[jobID]
+	if !ok || j.done() {
+		return Job{}, false
+	}
+	fn(j)
+	j.UpdatedAt = time.Now()
+	return *j, true
+}
+
+func (r *jobRecords) setStatus(jobID, status string) {
+	r.update(jobID, func(j *Job) { j.Status = status })
+}
+
+// cancel cancels the running jobs of the session other than except and
+// returns them.
+func (r *jobRecords) cancel(sessionID, except string) []Job {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var canceled []Job
+	for id, j := range r.jobs {
+		if j.sessionID == sessionID && id != except && !j.done() {
+			j.Status = JobCanceled
+			j.UpdatedAt = time.Now()
+			canceled = append(canceled, *j)
+		}
+	}
+	return canceled
+}
+
+// observe updates the job of ev from the event and returns the job as
+// updated.
+func (r *jobRecords) observe(ev events.Event) (Job, bool) {
+	if ev.JobID == "" {
+		return Job{}, false
+	}
+	return r.update(ev.JobID, func(j *Job) {
+		switch d := ev.Data.(type) {
+		case events.GenerateProgress:
+			j.Status = JobGenerating
+		case events.StoryboardReady:
+			j.Status = JobGenerating
+			j.Storyboard = d.Storyboard
+			j.Model = d.Model
+		case events.GenerateSuccess:
+			j.Script = d.Script
+			j.Model = d.Model
+		case events.RepairAttempt:
+			j.Status = JobCompiling
+			j.RepairAttempts = d.Attempt
+		case events.GenerateError:
+			j.Status = JobFailed
+			j.Error = &JobError{Message: d.Message, Details: d.Details, Reason: d.Reason}
+			if d.Model != "" {
+				j.Model = d.Model
+			}
+		case events.CompileSuccess:
+			j.Status = JobSucceeded
+			j.VideoURL = d.VideoURL
+			j.SubtitlesURL = d.SubtitlesURL
+			j.HasAudio = d.HasAudio
+			j.videoKey = d.VideoKey
+			j.subtitlesKey = d.SubtitlesKey
+		case events.CompileError:
+			j.Status = JobFailed
+			j.Error = &JobError{Message: d.Message, Details: d.Stderr, Line: d.Line}
+		}
+	})
+}
+
+// publish records ev in the state of its job and sends it to the client.
+func (a *App) publish(ev events.Event) {
+	if job, ok := a.records.observe(ev); ok {
+		a.remember(job)
+	}
+	if err := a.MsgRouter.SendMessage(ev); err != nil {
+		a.logger.Error("failed to send message to client channel", "session_id", ev.SessionID, "error", err)
+	}
+}
+
+// accepted answers a request that started jobID with 202 Accepted, pointing
+// to the job resource.
+func (a *App) accepted(w http.ResponseWriter, job Job) {
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	if err := WriteJSON(w, http.StatusAccepted, job); err != nil {
+		a.logger.Error("failed to write response", "error", err)
+	}
+}
+
+func (a *App) handleJob(w http.ResponseWriter, r *http.Request) {
+	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
+	if sessionID == "" {
+		a.serverError(w, fmt.Errorf("invalid, missing or expired session"))
+		return
+	}
+
+	job, ok := a.records.get(sessionID, r.PathValue("id"))
+	if !ok {
+		a.errorResponse(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if err := WriteJSON(w, http.StatusOK, job); err != nil {
+		a.logger.Error("failed to write response", "error", err)
+	}
+}
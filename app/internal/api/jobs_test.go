@@ -0,0 +1,50 @@
+package api
+
+import (
+	"manimatic/internal/api/events"
+	"testing"
+)
+
+func TestJobRecords(t *testing.T) {
+	r := newJobRecords()
+	r.create("s", "1", jobGenerate, "")
+
+	steps := []struct {
+		ev         events.Event
+		wantStatus string
+	}{
+		{events.NewGenerateProgress("s", "from manim import *"), JobGenerating},
+		{events.NewGenerateSuccess("s", "script", "fake-model", "default@v1"), JobGenerating},
+		{events.NewRepairAttempt("s", 1, 2, "NameError"), JobCompiling},
+		{events.NewCompileSuccess("s", events.CompileSuccess{VideoURL: "https://video", SubtitlesURL: "https://subtitles", HasAudio: true}), JobSucceeded},
+		// Finished jobs don't change anymore
+		{events.NewCompileError("s", "late", "", "", 0), JobSucceeded},
+	}
+	for i, step := range steps {
+		r.observe(step.ev.WithJobID("1"))
+		job, _ := r.get("s", "1")
+		if job.Status != step.wantStatus {
+			t.Errorf("step %d (%s): status = %s, want %s", i, step.ev.Kind, job.Status, step.wantStatus)
+		}
+	}
+	job, _ := r.get("s", "1")
+	if job.Script != "script" || job.Model != "fake-model" || job.VideoURL != "https://video" || job.SubtitlesURL != "https://subtitles" || !job.HasAudio || job.RepairAttempts != 1 || job.Error != nil {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	if _, ok := r.get("other", "1"); ok {
+		t.Error("jobs must not be visible to other sessions")
+	}
+
+	r.create("s", "2", jobCompile, "")
+	r.create("s", "3", jobCompile, "")
+	r.cancel("s", "3")
+	r.observe(events.NewCompileError("s", "Compilation Error", "", "Traceback", 4).WithJobID("3"))
+	if job, _ := r.get("s", "2"); job.Status != JobCanceled {
+		t.Errorf("superseded job status = %s, want %s", job.Status, JobCanceled)
+	}
+	job, _ = r.get("s", "3")
+	if job.Status != JobFailed || job.Error == nil || job.Error.Details != "Traceback" || job.Error.Line != 4 {
+		t.Errorf("unexpected failed job %+v", job)
+	}
+}
@@ -52,13 +52,22 @@ func (a *App) processNextVideoUpdateMessage(ctx context.Context) error {
 		_ = a.queueMgr.DeleteMessage(ctx, msg)
 		return err
 	}
-	a.logger.Debug("processing event", "kind", ev.Kind, "session_id", ev.SessionID)
+	a.logger.Debug("processing event", "kind", ev.Kind, "session_id", ev.SessionID, "job_id", ev.JobID)
 
-	err = a.MsgRouter.SendMessage(ev)
-	if err != nil {
-		a.logger.Error("Failed to send message to connection manager",
-			"session_id", ev.SessionID,
-			"error", err)
+	a.renders.observe(ev)
+	if ev.JobID != "" && !a.active.current(ev.SessionID, ev.JobID) {
+		a.logger.Debug("dropping result of superseded job", "session_id", ev.SessionID, "job_id", ev.JobID)
+		a.jobs.remove(ev.JobID)
+		return a.queueMgr.DeleteMessage(ctx, msg)
+	}
+	if a.handleRepair(ev) {
+		return a.queueMgr.DeleteMessage(ctx, msg)
+	}
+
+	a.publish(ev)
+	if ev.JobID != "" {
+		// The compilation result is the last event of a job
+		a.active.finish(ev.SessionID, ev.JobID)
 	}
 
 	return a.queueMgr.DeleteMessage(ctx, msg)
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"manimatic/internal/api/events"
+	"sync"
+	"time"
+)
+
+// renderCache remembers the video rendered for each generated script and its
+// narration so a cached generation in the same session can reuse it instead
+// of compiling the script again. Videos are kept per session since each
+// session's videos are stored under its own prefix.
+// The presigned URLs of an entry expire; App.cachedVideo presigns its keys
+// again on every hit.
+type renderCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	pending map[string]pendingRender // Job ID -> compile request being processed
+	videos  map[[sha256.Size]byte]renderedVideo
+}
+
+type pendingRender struct {
+	key       [sha256.Size]byte
+	createdAt time.Time
+}
+
+type renderedVideo struct {
+	result  events.CompileSuccess
+	expires time.Time
+}
+
+func newRenderCache(ttl time.Duration) *renderCache {
+	return &renderCache{
+		ttl:     ttl,
+		pending: make(map[string]pendingRender),
+		videos:  make(map[[sha256.Size]byte]renderedVideo),
+	}
+}
+
+// renderKey identifies the output of a compile request of a session.
+func renderKey(sessionID string, req events.CompileRequest) [sha256.Size]byte {
+	data, _ := json.Marshal(req)
+	h := sha256.New()
+	h.Write([]byte(sessionID))
+	h.Write([]byte{0})
+	h.Write(data)
+	return [sha256.Size]byte(h.Sum(nil))
+}
+
+// expect records that jobID of sessionID compiles req.
+func (c *renderCache) expect(sessionID, jobID string, req events.CompileRequest) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune()
+	c.pending[jobID] = pendingRender{key: renderKey(sessionID, req), createdAt: time.Now()}
+}
+
+// observe stores the video of a successful compilation of an expected job.
+func (c *renderCache) observe(ev events.Event) {
+	if c.ttl <= 0 || ev.JobID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[ev.JobID]
+	if !ok {
+		return
+	}
+	if ev.Kind == events.KindCompileFailed {
+		delete(c.pending, ev.JobID)
+		return
+	}
+	success, ok := ev.Data.(events.CompileSuccess)
+	if ev.Kind != events.KindCompileSucceeded || !ok {
+		return
+	}
+	delete(c.pending, ev.JobID)
+	c.videos[p.key] = renderedVideo{result: success, expires: time.Now().Add(c.ttl)}
+}
+
+// video returns the still valid result of a compilation of req in sessionID.
+func (c *renderCache) video(sessionID string, req events.CompileRequest) (events.CompileSuccess, bool) {
+	if c.ttl <= 0 {
+		return events.CompileSuccess{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.videos[renderKey(sessionID, req)]
+	if !ok || time.Now().After(v.expires) {
+		return events.CompileSuccess{}, false
+	}
+	return v.result, true
+}
+
+func (c *renderCache) prune() {
+	now := time.Now()
+	for id, p := range c.pending {
+		if now.Sub(p.createdAt) > generationJobTTL {
+			delete(c.pending, id)
+		}
+	}
+	for key, v := range c.videos {
+		if now.After(v.expires) {
+			delete(c.videos, key)
+		}
+	}
+}
+
+// cachedVideo returns the video rendered recently for req in sessionID, with
+// fresh links. Nothing is returned if they can't be presigned.
+func (a *App) cachedVideo(ctx context.Context, sessionID string, req events.CompileRequest) (events.CompileSuccess, bool) {
+	video, ok := a.renders.video(sessionID, req)
+	if !ok || a.videos == nil {
+		return video, ok
+	}
+	for _, link := range []struct {
+		key string
+		url *string
+	}{{video.VideoKey, &video.VideoURL}, {video.SubtitlesKey, &video.SubtitlesURL}} {
+		if link.key == "" {
+			continue
+		}
+		url, err := a.videos.PresignGet(ctx, link.key, videoURLTTL)
+		if err != nil {
+			a.logger.Error("failed to presign cached video", "session_id", sessionID, "key", link.key, "error", err)
+			return events.CompileSuccess{}, false
+		}
+		*link.url = url
+	}
+	return video, true
+}
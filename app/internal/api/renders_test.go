@@ -0,0 +1,30 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"manimatic/internal/api/events"
+	"testing"
+	"time"
+)
+
+func TestRenderCache(t *testing.T) {
+	a := &App{logger: slog.Default(), renders: newRenderCache(time.Minute), videos: fakePresigner{}}
+	ctx := context.Background()
+	req := events.CompileRequest{Script: "class A(Scene): pass"}
+
+	a.renders.expect("s1", "1", req)
+	video := events.CompileSuccess{VideoURL: "https://expired", VideoKey: "manim_outputs/s1/1.mp4"}
+	a.renders.observe(events.NewCompileSuccess("s1", video).WithJobID("1"))
+
+	got, ok := a.cachedVideo(ctx, "s1", req)
+	if !ok || got.VideoURL != "https://signed/manim_outputs/s1/1.mp4" {
+		t.Errorf("cachedVideo() = %+v, %t, want a freshly presigned video", got, ok)
+	}
+	if _, ok := a.cachedVideo(ctx, "s2", req); ok {
+		t.Error("expected another session not to reuse the video")
+	}
+	if _, ok := a.cachedVideo(ctx, "s1", events.CompileRequest{Script: "class B(Scene): pass"}); ok {
+		t.Error("expected another script not to reuse the video")
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"manimatic/internal/api/events"
+	"manimatic/internal/llm"
+	"sync"
+	"time"
+)
+
+// generationJobTTL bounds how long a job is remembered for repair. Results
+// that arrive later are forwarded to the client unchanged.
+const generationJobTTL = time.Hour
+
+// generationJob remembers where the script of a compile job came from so a
+// failed compilation can be sent back to the model that produced it.
+type generationJob struct {
+	sessionID string
+	prompt    string
+	model     string
+	script    string
+	narration []events.NarrationLine
+	attempts  int
+	createdAt time.Time
+}
+
+type jobTracker struct {
+	mu   sync.Mutex
+	jobs map[string]generationJob
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{jobs: make(map[string]generationJob)}
+}
+
+func (t *jobTracker) put(id string, job generationJob) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for jobID, j := range t.jobs {
+		if now.Sub(j.createdAt) > generationJobTTL {
+			delete(t.jobs, jobID)
+		}
+	}
+	t.jobs[id] = job
+}
+
+func (t *jobTracker) get(id string) (generationJob, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	return job, ok
+}
+
+func (t *jobTracker) remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.jobs, id)
+}
+
+// handleRepair intercepts compile results of LLM generated jobs. It returns
+// true when the event was consumed by a repair attempt and must not be
+// forwarded to the client.
+func (a *App) handleRepair(ev events.Event) bool {
+	if ev.JobID == "" || a.config.Processing.MaxRepairAttempts == 0 {
+		return false
+	}
+
+	switch ev.Kind {
+	case events.KindCompileSucceeded:
+		a.jobs.remove(ev.JobID)
+		return false
+
+	case events.KindCompileFailed:
+		job, ok := a.jobs.get(ev.JobID)
+		if !ok {
+			return false
+		}
+		compileErr, ok := ev.Data.(events.CompileError)
+		if !ok || job.attempts >= a.config.Processing.MaxRepairAttempts {
+			a.jobs.remove(ev.JobID)
+			return false
+		}
+
+		job.attempts++
+		a.jobs.put(ev.JobID, job)
+
+		update := events.NewRepairAttempt(job.sessionID, job.attempts, a.config.Processing.MaxRepairAttempts, compileErr.Message).WithJobID(ev.JobID)
+		a.publish(update)
+
+		go a.repair(ev, job, compileErr)
+		return true
+	}
+
+	return false
+}
+
+func (a *App) repair(failed events.Event, job generationJob, compileErr events.CompileError) {
+	jobCtx, ok := a.active.context(job.sessionID, failed.JobID)
+	if !ok {
+		a.jobs.remove(failed.JobID)
+		return
+	}
+	ctx, cancel := context.WithTimeout(jobCtx, a.config.LLM.RequestTimeout)
+	defer cancel()
+
+	a.logger.Info("repairing generated script", "session_id", job.sessionID, "job_id", failed.JobID, "attempt", job.attempts)
+
+	result, err := a.llmService.Repair(ctx, llm.RepairRequest{
+		Prompt: job.prompt,
+		Script: job.script,
+		Stderr: compileErr.Stderr,
+		Line:   compileErr.Line,
+	}, job.model)
+	if err == nil {
+		a.recordUsage(job.sessionID, result)
+	}
+	if err == nil && result.ValidInput && result.Code != "" {
+		result, err = a.validateGenerated(ctx, job.sessionID, job.prompt, result)
+	}
+	if err != nil || !result.ValidInput || result.Code == "" {
+		a.logger.Error("failed to repair script", "session_id", job.sessionID, "job_id", failed.JobID, "error", err)
+		a.jobs.remove(failed.JobID)
+		// Give the client the compile error that started the repair
+		a.failJob(job.sessionID, failed.JobID, failed)
+		return
+	}
+
+	a.logger.Info("repaired generated script", "session_id", job.sessionID, "job_id", failed.JobID, "model", result.Model, "prompt_version", result.Prompt)
+
+	if !a.active.queue(job.sessionID, failed.JobID) {
+		a.jobs.remove(failed.JobID)
+		return
+	}
+	job.script = result.Code
+	// Fixes rarely change the timing, so the narration is kept unless rewritten
+	if len(result.Narration) > 0 {
+		job.narration = narrationTrack(result.Narration)
+	}
+	a.jobs.put(failed.JobID, job)
+	compileReq := events.CompileRequest{Script: result.Code, Narration: job.narration}
+	a.renders.expect(job.sessionID, failed.JobID, compileReq)
+
+	workerTask := events.NewCompileRequest(job.sessionID, result.Code, job.narration).WithJobID(failed.JobID)
+	if err := a.queueMgr.EnqeueMsg(ctx, &workerTask); err != nil {
+		a.logger.Error("failed to enqueue message", "error", err, "job_id", failed.JobID)
+		a.jobs.remove(failed.JobID)
+		a.failJob(job.sessionID, failed.JobID, failed)
+		return
+	}
+
+	a.notify(job.sessionID, failed.JobID, events.NewGenerateSuccess(job.sessionID, result.Code, result.Model, result.Prompt))
+}
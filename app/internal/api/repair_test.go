@@ -0,0 +1,137 @@
+package api
+
+import (
+	"manimatic/internal/api/events"
+	"manimatic/internal/llm"
+	"manimatic/internal/llm/fake"
+	"slices"
+	"testing"
+	"time"
+)
+
+const repairedScript = `from manim import *
+
+class Circle(Scene):
+    def construct(self):
+        self.play(Create(Circle(radius=2)))
+`
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts int
+		repair      fake.Fixture // Answers the repair prompts
+		failures    int          // Compilations failing in a row
+		wantEvents  []string
+		wantScripts []string // Scripts queued after the first one
+		wantStatus  string
+	}{
+		{
+			name:        "repaired",
+			maxAttempts: 2,
+			repair:      fake.Fixture{Response: &llm.Response{Code: repairedScript, ValidInput: true}},
+			failures:    1,
+			wantEvents:  []string{events.KindRepairAttempt, events.KindGenerateSucceeded},
+			wantScripts: []string{repairedScript},
+			wantStatus:  JobCompiling,
+		},
+		{
+			name:        "gives up after the last attempt",
+			maxAttempts: 1,
+			repair:      fake.Fixture{Response: &llm.Response{Code: repairedScript, ValidInput: true}},
+			failures:    2,
+			wantEvents:  []string{events.KindRepairAttempt, events.KindGenerateSucceeded, events.KindCompileFailed},
+			wantScripts: []string{repairedScript},
+			wantStatus:  JobFailed,
+		},
+		{
+			name:        "repaired script rejected",
+			maxAttempts: 2,
+			repair:      fake.Fixture{Response: &llm.Response{Code: rejectedScript, ValidInput: true}},
+			failures:    1,
+			wantEvents:  []string{events.KindRepairAttempt, events.KindCompileFailed},
+			wantStatus:  JobFailed,
+		},
+		{
+			name:        "repair fails",
+			maxAttempts: 2,
+			repair:      fake.Fixture{Error: "overloaded", Status: 400},
+			failures:    1,
+			wantEvents:  []string{events.KindRepairAttempt, events.KindCompileFailed},
+			wantStatus:  JobFailed,
+		},
+		{
+			name:       "disabled",
+			failures:   1,
+			wantEvents: []string{events.KindCompileFailed},
+			wantStatus: JobFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Repair prompts quote the original one, so they are matched first
+			tt.repair.Match = "failed to compile"
+			a, queue := newTestApp(t, tt.repair, fake.Fixture{
+				Match:    "circle",
+				Response: &llm.Response{Code: circleScript, ValidInput: true},
+			})
+			a.config.Processing.MaxRepairAttempts = tt.maxAttempts
+
+			job := postGenerate(t, a, `{"prompt": "draw a circle"}`)
+			task, _ := nextTask(t, queue)
+			if _, ok := a.jobs.get(job.ID); ok != (tt.maxAttempts > 0) {
+				t.Errorf("job remembered for repair: %t, want %t", ok, tt.maxAttempts > 0)
+			}
+			client, cleanup := a.MsgRouter.AddClient(task.SessionID)
+			defer cleanup()
+
+			var kinds []string
+			var scripts []string
+			for i := range tt.failures {
+				deliver(t, a, queue, events.NewCompileError(task.SessionID, "compilation failed", "", "NameError: name 'Circel' is not defined", 5).WithJobID(job.ID))
+				if i == tt.failures-1 {
+					break
+				}
+				// Wait for the repaired script before it fails again
+				retry, req := nextTask(t, queue)
+				if retry.JobID != job.ID {
+					t.Fatalf("repaired script queued as job %s, want %s", retry.JobID, job.ID)
+				}
+				scripts = append(scripts, req.Script)
+			}
+			for len(kinds) < len(tt.wantEvents) {
+				select {
+				case ev := <-client:
+					if ev.JobID != job.ID {
+						t.Errorf("event %s of job %s, want %s", ev.Kind, ev.JobID, job.ID)
+					}
+					kinds = append(kinds, ev.Kind)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("got events %v, want %v", kinds, tt.wantEvents)
+				}
+			}
+			if !slices.Equal(kinds, tt.wantEvents) {
+				t.Errorf("got events %v, want %v", kinds, tt.wantEvents)
+			}
+
+			if tt.wantStatus == JobCompiling {
+				retry, req := nextTask(t, queue)
+				if retry.JobID != job.ID {
+					t.Fatalf("repaired script queued as job %s, want %s", retry.JobID, job.ID)
+				}
+				scripts = append(scripts, req.Script)
+			}
+			if !slices.Equal(scripts, tt.wantScripts) {
+				t.Errorf("queued scripts %q, want %q", scripts, tt.wantScripts)
+			}
+
+			record := waitJob(t, a, job.ID, tt.wantStatus)
+			if record.RepairAttempts != min(tt.failures, tt.maxAttempts) {
+				t.Errorf("repair attempts = %d, want %d", record.RepairAttempts, min(tt.failures, tt.maxAttempts))
+			}
+			if _, ok := a.jobs.get(job.ID); ok != (tt.wantStatus == JobCompiling) {
+				t.Errorf("job still remembered for repair: %t", ok)
+			}
+		})
+	}
+}
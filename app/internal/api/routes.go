@@ -19,12 +19,20 @@ func (a *App) setupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("POST /generate", a.HandleGenerate)
+	mux.HandleFunc("POST /refine", a.HandleRefine)
+	mux.HandleFunc("POST /storyboard", a.handleStoryboard)
+	mux.HandleFunc("GET /jobs/{id}", a.handleJob)
 	mux.HandleFunc("GET /events", a.sseHandler)
 	mux.HandleFunc("GET /models", a.modelsHandler)
 
 	mux.HandleFunc("GET /healthz", healthCheckHandler)
 	mux.HandleFunc("GET /features", a.featuresHandler)
 
+	if a.history != nil {
+		mux.HandleFunc("GET /history", a.handleHistory)
+		mux.HandleFunc("GET /history/{id}", a.handleHistoryEntry)
+	}
+
 	if a.config.Processing.Features.IsEnabled(features.UserCompile) {
 		mux.HandleFunc("POST /compile", a.handleCompile)
 	}
@@ -1,20 +1,52 @@
 package session
 
 import (
+	"fmt"
+	"manimatic/internal/config"
 	"net/http"
-	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/alexedwards/scs/v2/memstore"
 )
 
-func New() *scs.SessionManager {
+// New returns a session manager keeping sessions in store, or in memory if
+// store is nil.
+func New(cfg config.SessionConfig, store scs.Store) *scs.SessionManager {
 	sessionManager := scs.New()
-	sessionManager.Lifetime = 24 * time.Hour
-	sessionManager.Cookie.Name = "MANIMATIC_SS"
+	sessionManager.Lifetime = cfg.Lifetime
+	sessionManager.IdleTimeout = cfg.IdleTimeout
+	sessionManager.Cookie.Name = cfg.CookieName
+	sessionManager.Cookie.Domain = cfg.CookieDomain
 	sessionManager.Cookie.Persist = true
-	sessionManager.Store = memstore.New()
+	sessionManager.Store = store
+	if store == nil {
+		sessionManager.Store = memstore.New()
+	}
 	sessionManager.Cookie.SameSite = http.SameSiteNoneMode
 	sessionManager.Cookie.Secure = true
 	return sessionManager
 }
+
+// OpenStore opens the session store selected by cfg. The returned function
+// releases it.
+func OpenStore(cfg config.SessionConfig) (scs.Store, func() error, error) {
+	switch cfg.Store {
+	case "sqlite":
+		s, err := OpenSQLite(cfg.Path, cleanupInterval)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	case "redis":
+		s, err := NewRedis(cfg.RedisURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, s.Close, nil
+	case "memory":
+		s := memstore.New()
+		return s, func() error { s.StopCleanup(); return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown session store %q", cfg.Store)
+	}
+}
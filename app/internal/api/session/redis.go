@@ -0,0 +1,238 @@
+package session
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redis store defaults
+const (
+	redisKeyPrefix = "manimatic:session:"
+	redisTimeout   = 5 * time.Second
+	redisIdleConns = 8
+)
+
+// RedisStore keeps sessions in a server speaking the Redis protocol (Redis,
+// Valkey, KeyDB...), so several API instances can share them. Sessions expire
+// through the key TTL.
+type RedisStore struct {
+	addr     string
+	host     string // Server name checked over TLS
+	username string
+	password string
+	db       int
+	tls      bool
+	idle     chan *redisConn
+}
+
+// NewRedis returns a store for the server at rawURL, in the form
+// redis://[user:password@]host:port[/db] or rediss:// for TLS. Connections are
+// made on first use.
+func NewRedis(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid redis URL scheme %q: expected redis or rediss", u.Scheme)
+	}
+	s := &RedisStore{addr: u.Host, host: u.Hostname(), tls: u.Scheme == "rediss", idle: make(chan *redisConn, redisIdleConns)}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return s, nil
+}
+
+// Find returns the data of an unexpired session.
+func (s *RedisStore) Find(token string) ([]byte, bool, error) {
+	reply, err := s.do("GET", redisKeyPrefix+token)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find session: %w", err)
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+	b, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("failed to find session: unexpected reply %v", reply)
+	}
+	return b, true, nil
+}
+
+// Commit saves the data of a session until expiry.
+func (s *RedisStore) Commit(token string, b []byte, expiry time.Time) error {
+	ttl := time.Until(expiry).Milliseconds()
+	if ttl <= 0 {
+		return s.Delete(token)
+	}
+	if _, err := s.do("SET", redisKeyPrefix+token, string(b), "PX", strconv.FormatInt(ttl, 10)); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(token string) error {
+	if _, err := s.do("DEL", redisKeyPrefix+token); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// Close closes the idle connections.
+func (s *RedisStore) Close() error {
+	for {
+		select {
+		case c := <-s.idle:
+			c.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// do runs a command on an idle connection or a new one. Connections that
+// failed are dropped rather than reused.
+func (s *RedisStore) do(args ...string) (any, error) {
+	var c *redisConn
+	select {
+	case c = <-s.idle:
+	default:
+		var err error
+		if c, err = s.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.do(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.Close()
+		return nil, err
+	}
+	select {
+	case s.idle <- c:
+	default:
+		c.Close()
+	}
+	return reply, err
+}
+
+func (s *RedisStore) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if s.tls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, &tls.Config{ServerName: s.host})
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	c := &redisConn{Conn: conn, r: bufio.NewReader(conn)}
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	if s.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(s.db)); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to select redis database: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// redisError is an error reply of the server. The connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// do sends a command and reads its reply: a string, an int64, a []byte, nil
+// for a missing value or a []any.
+func (c *redisConn) do(args ...string) (any, error) {
+	c.SetDeadline(time.Now().Add(redisTimeout))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.r)
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// cleanupInterval is how often expired sessions are deleted from the SQLite
+// store.
+const cleanupInterval = 5 * time.Minute
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	token  TEXT PRIMARY KEY,
+	data   BLOB NOT NULL,
+	expiry INTEGER NOT NULL -- Unix nanoseconds
+);
+CREATE INDEX IF NOT EXISTS sessions_expiry ON sessions (expiry);
+`
+
+// SQLiteStore keeps sessions in an SQLite database file, so they survive
+// restarts of a single API instance.
+type SQLiteStore struct {
+	db   *sql.DB
+	stop chan struct{}
+	done chan struct{}
+}
+
+// OpenSQLite opens the database at path, creating it and its directory if
+// needed. Expired sessions are deleted every interval.
+func OpenSQLite(path string, interval time.Duration) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create session directory: %w", err)
+		}
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session schema: %w", err)
+	}
+
+	s := &SQLiteStore{db: db, stop: make(chan struct{}), done: make(chan struct{})}
+	go s.cleanup(interval)
+	return s, nil
+}
+
+// Find returns the data of an unexpired session.
+func (s *SQLiteStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+	err := s.db.QueryRow(`SELECT data FROM sessions WHERE token = ? AND expiry > ?`, token, time.Now().UnixNano()).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find session: %w", err)
+	}
+	return b, true, nil
+}
+
+// Commit saves the data of a session until expiry.
+func (s *SQLiteStore) Commit(token string, b []byte, expiry time.Time) error {
+	_, err := s.db.Exec(`INSERT INTO sessions (token, data, expiry) VALUES (?, ?, ?)
+		ON CONFLICT (token) DO UPDATE SET data = excluded.data, expiry = excluded.expiry`,
+		token, b, expiry.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Delete(token string) error {
+	if _, err := s.db.Exec(`DELETE FROM sessions WHERE token = ?`, token); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// Close stops the cleanup and closes the database.
+func (s *SQLiteStore) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) cleanup(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if _, err := s.db.Exec(`DELETE FROM sessions WHERE expiry <= ?`, time.Now().UnixNano()); err != nil {
+				slog.Error("failed to delete expired sessions", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// fakeRedis is an in-process server answering the few commands the redis
+// store sends.
+type fakeRedis struct {
+	mu       sync.Mutex
+	password string
+	values   map[string][]byte
+	expiry   map[string]time.Time
+}
+
+func startFakeRedis(t *testing.T, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{password: password, values: make(map[string][]byte), expiry: make(map[string]time.Time)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]any) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		var out string
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "AUTH":
+			authed = args[len(args)-1] == f.password
+			out = "+OK\r\n"
+			if !authed {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			out = "+OK\r\n"
+		default:
+			out = f.run(args)
+		}
+		conn.Write([]byte(out))
+	}
+}
+
+func (f *fakeRedis) run(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := args[1]
+	if exp, ok := f.expiry[key]; ok && time.Now().After(exp) {
+		delete(f.values, key)
+		delete(f.expiry, key)
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		v, ok := f.values[key]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(v)) + "\r\n" + string(v) + "\r\n"
+	case "SET":
+		f.values[key] = []byte(args[2])
+		delete(f.expiry, key)
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			f.expiry[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := f.values[key]
+		delete(f.values, key)
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) (scs.Store, func() error){
+		"sqlite": func(t *testing.T) (scs.Store, func() error) {
+			s, err := OpenSQLite(filepath.Join(t.TempDir(), "sessions", "sessions.db"), time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s, s.Close
+		},
+		"redis": func(t *testing.T) (scs.Store, func() error) {
+			s, err := NewRedis("redis://:secret@" + startFakeRedis(t, "secret") + "/2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s, s.Close
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			s, close := open(t)
+			defer close()
+
+			data := []byte("session\r\ndata")
+			if err := s.Commit("live", data, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Commit("expired", data, time.Now().Add(-time.Second)); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				token     string
+				wantFound bool
+			}{
+				{"live", true},
+				{"expired", false},
+				{"unknown", false},
+			}
+			for _, tt := range tests {
+				b, found, err := s.Find(tt.token)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if found != tt.wantFound || (found && !bytes.Equal(b, data)) {
+					t.Errorf("Find(%q) = %q, %v, want found %v", tt.token, b, found, tt.wantFound)
+				}
+			}
+
+			if err := s.Delete("live"); err != nil {
+				t.Fatal(err)
+			}
+			if _, found, _ := s.Find("live"); found {
+				t.Error("deleted session was found")
+			}
+		})
+	}
+}
+
+func TestRedisWrongPassword(t *testing.T) {
+	s, err := NewRedis("redis://:wrong@" + startFakeRedis(t, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, _, err := s.Find("token"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Find() error = %v, want WRONGPASS", err)
+	}
+}
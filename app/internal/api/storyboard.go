@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"manimatic/internal/api/events"
+	"manimatic/internal/api/middleware"
+	"manimatic/internal/llm"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Generation modes
+const (
+	modeDirect     = "direct"     // The script is generated from the prompt in one call
+	modeStoryboard = "storyboard" // A storyboard is planned first and the script follows it
+)
+
+// storyboardReviewTTL bounds how long a storyboard waits to be submitted
+// back. The session's job is usually superseded long before.
+const storyboardReviewTTL = time.Hour
+
+// storyboardReview is a storyboard mode job waiting for its storyboard to be
+// reviewed before the script is generated.
+type storyboardReview struct {
+	jobID     string
+	req       GenerateRequest
+	createdAt time.Time
+}
+
+type storyboardReviews struct {
+	mu       sync.Mutex
+	sessions map[string]storyboardReview
+}
+
+func newStoryboardReviews() *storyboardReviews {
+	return &storyboardReviews{sessions: make(map[string]storyboardReview)}
+}
+
+func (r *storyboardReviews) put(sessionID string, review storyboardReview) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, rv := range r.sessions {
+		if now.Sub(rv.createdAt) > storyboardReviewTTL {
+			delete(r.sessions, id)
+		}
+	}
+	r.sessions[sessionID] = review
+}
+
+// take returns and forgets the review of the session.
+func (r *storyboardReviews) take(sessionID string) (storyboardReview, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	review, ok := r.sessions[sessionID]
+	delete(r.sessions, sessionID)
+	if !ok || time.Since(review.createdAt) > storyboardReviewTTL {
+		return storyboardReview{}, false
+	}
+	return review, true
+}
+
+// planStoryboard runs the first step of the storyboard mode and sends the
+// storyboard to the client. It reports whether the script can be generated
+// right away; a storyboard awaiting review is kept until it is submitted
+// back to handleStoryboard.
+func (a *App) planStoryboard(ctx context.Context, sessionID, jobID string, req GenerateRequest) (llm.Storyboard, bool) {
+	board, err := a.llmService.Storyboard(ctx, req.Prompt, req.Images, req.Model, req.Params)
+	if err != nil {
+		if a.active.current(sessionID, jobID) {
+			a.logger.Error("failed to generate storyboard", "error", err)
+		}
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to generate storyboard", err.Error(), llm.FailedModel(err, a.requestedModel(req.Model))))
+		return llm.Storyboard{}, false
+	}
+	a.recordUsage(sessionID, llm.Response{Model: board.Model, Usage: board.Usage})
+	if !board.ValidInput {
+		a.logger.Info("storyboard flagged as invalid", "prompt", req.Prompt)
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to plan a scene for the given prompt", board.Warnings, board.Model))
+		return llm.Storyboard{}, false
+	}
+
+	data, err := json.Marshal(board)
+	if err != nil {
+		a.failJob(sessionID, jobID, events.NewGenerateError(sessionID, "failed to generate storyboard", err.Error(), board.Model))
+		return llm.Storyboard{}, false
+	}
+	if !a.notify(sessionID, jobID, events.NewStoryboardReady(sessionID, data, board.Model, req.Review)) {
+		return llm.Storyboard{}, false
+	}
+	a.logger.Info("planned storyboard", "session_id", sessionID, "job_id", jobID, "model", board.Model, "prompt_version", board.Prompt, "beats", len(board.Beats), "review", req.Review)
+	if req.Review {
+		a.reviews.put(sessionID, storyboardReview{jobID: jobID, req: req, createdAt: time.Now()})
+		return llm.Storyboard{}, false
+	}
+	return board, true
+}
+
+// handleStoryboard generates the script of a storyboard that was held for
+// review, following the storyboard as submitted by the client.
+func (a *App) handleStoryboard(w http.ResponseWriter, r *http.Request) {
+	var req StoryboardRequest
+
+	if err := ReadJSON(w, r, &req); err != nil {
+		a.badRequestResponse(w, "invalid request body")
+		return
+	}
+	if err := req.Storyboard.Validate(); err != nil {
+		a.badRequestResponse(w, err.Error())
+		return
+	}
+
+	sessionID := a.sm.GetString(r.Context(), middleware.UserSessionTokenKey)
+	if sessionID == "" {
+		a.serverError(w, fmt.Errorf("invalid, missing or expired session"))
+		return
+	}
+
+	review, ok := a.reviews.take(sessionID)
+	var jobCtx context.Context
+	if ok {
+		jobCtx, ok = a.active.context(sessionID, review.jobID)
+	}
+	if !ok {
+		a.errorResponse(w, http.StatusConflict, "no storyboard is awaiting review")
+		return
+	}
+
+	if job, ok := a.records.get(sessionID, review.jobID); ok {
+		a.accepted(w, job)
+	} else {
+		w.WriteHeader(http.StatusAccepted)
+	}
+
+	if !a.allowGeneration(sessionID, review.jobID, review.req.Model) {
+		a.active.finish(sessionID, review.jobID)
+		return
+	}
+
+	board := req.Storyboard
+	board.ValidInput = true
+	go func() {
+		ctx, cancel := context.WithTimeout(jobCtx, a.config.LLM.RequestTimeout)
+		defer cancel()
+		// The storyboard may have been edited since the prompt was moderated
+		if !a.moderate(ctx, sessionID, review.jobID, llm.StoryboardPrompt(review.req.Prompt, board), review.req.Model) {
+			return
+		}
+		a.generateScript(ctx, sessionID, review.jobID, review.req, &board)
+	}()
+}
@@ -0,0 +1,140 @@
+package usage
+
+import (
+	"errors"
+	"manimatic/internal/config"
+	"manimatic/internal/llm"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionBudget = errors.New("session budget exhausted")
+	ErrDailyBudget   = errors.New("daily budget exhausted")
+)
+
+// Totals is the usage accumulated over a number of calls.
+type Totals struct {
+	Calls int       `json:"calls"`
+	Usage llm.Usage `json:"usage"`
+	Cost  float64   `json:"cost_usd"`
+}
+
+func (t *Totals) add(u llm.Usage, cost float64) {
+	t.Calls++
+	t.Usage = t.Usage.Add(u)
+	t.Cost += cost
+}
+
+type session struct {
+	Totals
+	lastUsed time.Time
+}
+
+// Tracker accumulates token usage and cost per session, per UTC day and
+// overall, and enforces the session and daily budgets. A budget of zero is
+// unlimited.
+type Tracker struct {
+	mu            sync.Mutex
+	prices        map[string]config.Price
+	sessionBudget float64
+	dailyBudget   float64
+	sessions      map[string]*session
+	idleTTL       time.Duration
+	day           string // UTC date of today's totals
+	today         Totals
+	total         Totals
+	now           func() time.Time
+}
+
+// New returns a tracker forgetting the usage of sessions that have not been
+// used for sessionLifetime, by when the session itself has expired.
+func New(prices map[string]config.Price, sessionBudget, dailyBudget float64, sessionLifetime time.Duration) *Tracker {
+	return &Tracker{
+		prices:        prices,
+		sessionBudget: sessionBudget,
+		dailyBudget:   dailyBudget,
+		sessions:      make(map[string]*session),
+		idleTTL:       sessionLifetime,
+		now:           time.Now,
+	}
+}
+
+// Allow reports whether the session may make another call, returning
+// ErrDailyBudget or ErrSessionBudget once a budget is spent.
+func (t *Tracker) Allow(sessionID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover()
+	if t.dailyBudget > 0 && t.today.Cost >= t.dailyBudget {
+		return ErrDailyBudget
+	}
+	if s, ok := t.sessions[sessionID]; ok && t.sessionBudget > 0 && s.Cost >= t.sessionBudget {
+		return ErrSessionBudget
+	}
+	return nil
+}
+
+// Record adds the usage of a call to model and returns its cost. Models
+// without a price are counted at no cost.
+func (t *Tracker) Record(sessionID, model string, u llm.Usage) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover()
+	t.prune()
+	cost := t.prices[model].Cost(u.PromptTokens, u.CompletionTokens)
+
+	s, ok := t.sessions[sessionID]
+	if !ok {
+		s = &session{}
+		t.sessions[sessionID] = s
+	}
+	s.add(u, cost)
+	s.lastUsed = t.now()
+	t.today.add(u, cost)
+	t.total.add(u, cost)
+	return cost
+}
+
+// Session returns the usage of a session.
+func (t *Tracker) Session(sessionID string) Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.sessions[sessionID]; ok {
+		return s.Totals
+	}
+	return Totals{}
+}
+
+// Today returns the usage of all sessions since midnight UTC.
+func (t *Tracker) Today() Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	return t.today
+}
+
+// Total returns the usage of all sessions since the tracker was created.
+func (t *Tracker) Total() Totals {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+func (t *Tracker) rollover() {
+	if day := t.now().UTC().Format(time.DateOnly); day != t.day {
+		t.day = day
+		t.today = Totals{}
+	}
+}
+
+func (t *Tracker) prune() {
+	now := t.now()
+	for id, s := range t.sessions {
+		if now.Sub(s.lastUsed) > t.idleTTL {
+			delete(t.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package usage
+
+import (
+	"manimatic/internal/config"
+	"manimatic/internal/llm"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTrackerBudgets(t *testing.T) {
+	now := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	tr := New(map[string]config.Price{"gpt-4o": {Prompt: 2.5, Completion: 10}}, 0.02, 0.03, 24*time.Hour)
+	tr.now = func() time.Time { return now }
+
+	cost := tr.Record("a", "gpt-4o", llm.Usage{PromptTokens: 4000, CompletionTokens: 1000})
+	if math.Abs(cost-0.02) > 1e-9 {
+		t.Fatalf("cost = %v, want 0.02", cost)
+	}
+	if err := tr.Allow("a"); err != ErrSessionBudget {
+		t.Errorf("Allow(a) = %v, want ErrSessionBudget", err)
+	}
+	if err := tr.Allow("b"); err != nil {
+		t.Errorf("Allow(b) = %v, want nil", err)
+	}
+
+	// Unpriced models are tracked at no cost
+	tr.Record("b", "local-model", llm.Usage{PromptTokens: 1e6})
+	tr.Record("b", "gpt-4o", llm.Usage{CompletionTokens: 1000})
+	if err := tr.Allow("b"); err != ErrDailyBudget {
+		t.Errorf("Allow(b) = %v, want ErrDailyBudget", err)
+	}
+	if got := tr.Session("b"); got.Calls != 2 || got.Usage.PromptTokens != 1e6 {
+		t.Errorf("Session(b) = %+v", got)
+	}
+
+	// The daily budget resets at midnight UTC, session budgets don't
+	now = now.Add(2 * time.Hour)
+	if err := tr.Allow("b"); err != nil {
+		t.Errorf("Allow(b) after midnight = %v, want nil", err)
+	}
+	if err := tr.Allow("a"); err != ErrSessionBudget {
+		t.Errorf("Allow(a) after midnight = %v, want ErrSessionBudget", err)
+	}
+	if got := tr.Total(); got.Calls != 3 {
+		t.Errorf("Total().Calls = %d, want 3", got.Calls)
+	}
+}
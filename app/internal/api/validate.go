@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"manimatic/internal/llm"
+	"manimatic/internal/worker/manimexec/security"
+)
+
+// validateGenerated runs the worker's parse and security checks on a
+// generated script so rejected scripts never reach the queue. A rejected
+// script is sent back to the model that wrote it with the validation error,
+// up to MaxValidationAttempts times. The returned error is the last
+// validation failure.
+func (a *App) validateGenerated(ctx context.Context, sessionID, prompt string, result llm.Response) (llm.Response, error) {
+	for attempt := 1; ; attempt++ {
+		err := a.validator.ValidateScript(result.Code)
+		if err == nil {
+			return result, nil
+		}
+		if attempt > a.config.Processing.MaxValidationAttempts {
+			return result, err
+		}
+
+		a.logger.Info("generated script failed validation, regenerating",
+			"session_id", sessionID, "model", result.Model, "attempt", attempt, "error", err)
+
+		var line int
+		var valErr *security.ValidationError
+		if errors.As(err, &valErr) {
+			line = valErr.Line
+		}
+		fixed, genErr := a.llmService.Repair(ctx, llm.RepairRequest{
+			Prompt:    prompt,
+			Script:    result.Code,
+			Violation: err.Error(),
+			Line:      line,
+		}, result.Model)
+		if genErr != nil {
+			return result, fmt.Errorf("%w (regeneration failed: %v)", err, genErr)
+		}
+		a.recordUsage(sessionID, fixed)
+		if !fixed.ValidInput || fixed.Code == "" {
+			return result, err
+		}
+		result = fixed
+	}
+}
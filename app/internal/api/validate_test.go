@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"manimatic/internal/llm"
+	"manimatic/internal/llm/fake"
+	"strings"
+	"testing"
+)
+
+func TestValidateGenerated(t *testing.T) {
+	const violation = "rejected by the sandbox"
+	tests := []struct {
+		name        string
+		maxAttempts int
+		fixtures    []fake.Fixture
+		wantScript  string
+		wantErr     string
+	}{
+		{
+			name:        "repaired",
+			maxAttempts: 1,
+			fixtures:    []fake.Fixture{{Match: violation, Response: &llm.Response{Code: circleScript, ValidInput: true}}},
+			wantScript:  circleScript,
+		},
+		{
+			name:        "still rejected after the last attempt",
+			maxAttempts: 2,
+			fixtures: []fake.Fixture{
+				{Match: violation, Times: 2, Response: &llm.Response{Code: rejectedScript, ValidInput: true}},
+				// Only reached by a third attempt
+				{Match: violation, Response: &llm.Response{Code: circleScript, ValidInput: true}},
+			},
+			wantScript: rejectedScript,
+			wantErr:    "import of 'os' is not allowed",
+		},
+		{
+			name:        "repair fails",
+			maxAttempts: 1,
+			fixtures:    []fake.Fixture{{Match: violation, Error: "overloaded", Status: 400}},
+			wantScript:  rejectedScript,
+			wantErr:     "regeneration failed",
+		},
+		{
+			name:       "disabled",
+			wantScript: rejectedScript,
+			wantErr:    "import of 'os' is not allowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t, tt.fixtures...)
+			a.config.Processing.MaxValidationAttempts = tt.maxAttempts
+
+			result, err := a.validateGenerated(context.Background(), "s", "draw a circle", llm.Response{Code: rejectedScript, ValidInput: true, Model: "fake"})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got %v, want error containing %q", err, tt.wantErr)
+			}
+			if result.Code != tt.wantScript {
+				t.Errorf("got script %q, want %q", result.Code, tt.wantScript)
+			}
+		})
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"manimatic/internal/api/features"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -33,10 +35,72 @@ type LogConfig struct {
 }
 
 type ProcessingConfig struct {
-	MaxConcurrency   int
-	EnableModeration bool
-	FeaturesFlag     string
-	Features         *features.Features
+	MaxConcurrency        int
+	EnableModeration      bool
+	ModerationBackend     string // openai or rules
+	ModerationRules       string // Rules file of the rules backend
+	MaxRepairAttempts     int
+	MaxValidationAttempts int
+	ConversationTurns     int
+	VideoCacheTTL         time.Duration // How long rendered videos are reused for identical scripts, 0 disables
+	FeaturesFlag          string
+	Features              *features.Features
+}
+
+// LLMConfig controls how calls to the language model providers are made
+type LLMConfig struct {
+	Fallbacks        map[string][]string // Model -> models tried in order when it fails
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	RetryAttempts    int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	AttemptTimeout   time.Duration // Deadline for a single provider call
+	RequestTimeout   time.Duration // Deadline for a whole generation, including retries and fallbacks
+	PromptsDir       string        // Directory of <name>@<version>.tmpl system prompt templates
+	PromptDefault    string        // Template used by models without an assignment
+	PromptModels     map[string]string
+	FewShotExamples  int // Curated examples sent with each request
+	MaxCandidates    int // Upper bound on candidates a request may ask for
+	Prices           map[string]Price
+	SessionBudget    float64 // USD per session, 0 for no limit
+	DailyBudget      float64 // USD per UTC day across all sessions, 0 for no limit
+	Mode             string  // live, fake, record or replay
+	FakeFixtures     string  // Fixture file of the fake provider
+	CassetteDir      string  // Directory of record/replay cassettes, one file per provider
+	Cache            string  // Response cache backend: memory, file or none
+	CacheDir         string
+	CacheSize        int
+	CacheTTL         time.Duration
+
+	Discovery         bool          // List the OpenAI, xAI and compatible provider models from their APIs
+	DiscoveryInterval time.Duration // How often discovered models are refreshed
+	OpenAIModels      ModelPatterns
+	XAIModels         ModelPatterns
+
+	fallbacksList    string
+	promptModelsList string
+	pricesList       string
+}
+
+// Price is the cost of a model in USD per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Cost returns the cost in USD of the tokens of a call.
+func (p Price) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// ModelPatterns select the discovered models of a provider. Patterns use
+// path.Match syntax, e.g. gpt-4o*; deny patterns win over allow patterns.
+type ModelPatterns struct {
+	Allow     []string
+	Deny      []string
+	allowList string
+	denyList  string
 }
 
 type APIKeyConfig struct {
@@ -46,8 +110,40 @@ type APIKeyConfig struct {
 	IsSet      bool
 }
 
+// CompatProviderConfig describes an OpenAI-compatible endpoint. Each entry
+// listed in LLM_COMPAT_PROVIDERS is configured from variables prefixed with
+// its upper-cased name, e.g. OLLAMA_BASE_URL, OLLAMA_MODELS, OLLAMA_API_KEY.
+type CompatProviderConfig struct {
+	Name       string
+	BaseURL    string
+	PathPrefix string
+	Models     []string
+	APIKey     APIKeyConfig
+	Discovered ModelPatterns // Models registered from its /v1/models endpoint when discovery is on
+}
+
 type WorkerMediaConfig struct {
-	BaseDir string
+	BaseDir         string
+	SubtitlesFormat string // vtt or srt
+	TTSEngine       string // none, espeak-ng or piper
+	TTSVoice        string // espeak-ng voice name or piper voice model path
+}
+
+// HistoryConfig controls where the job history of sessions is kept
+type HistoryConfig struct {
+	Store string // sqlite, memory or none
+	Path  string // Database file of the sqlite store
+}
+
+// SessionConfig controls how user sessions are stored and their cookie
+type SessionConfig struct {
+	Store        string // memory, sqlite or redis
+	Path         string // Database file of the sqlite store
+	RedisURL     string // redis://[user:password@]host:port[/db] of the redis store
+	Lifetime     time.Duration
+	IdleTimeout  time.Duration // 0 keeps idle sessions for their whole lifetime
+	CookieName   string
+	CookieDomain string
 }
 
 type Config struct {
@@ -55,9 +151,16 @@ type Config struct {
 	AWS        AWSConfig
 	Logging    LogConfig
 	Processing ProcessingConfig
+	LLM        LLMConfig
 	OpenAI     APIKeyConfig
 	XAI        APIKeyConfig
+	Anthropic  APIKeyConfig
+	Compat     []CompatProviderConfig
 	Worker     WorkerMediaConfig
+	History    HistoryConfig
+	Session    SessionConfig
+
+	compatProviders string
 }
 
 func (c *Config) registerServerConfig(r *Register) {
@@ -84,10 +187,48 @@ func (c *Config) registerLoggingConfig(r *Register) {
 
 func (c *Config) registerProcessingConfig(r *Register) {
 	r.Int(&c.Processing.MaxConcurrency, "MAX_CONCURRENCY", "Max concurrent job processing", runtime.NumCPU())
-	r.Bool(&c.Processing.EnableModeration, "ENABLE_MODERATION", "Use the OpenAI moderation endpoint", false)
+	r.Bool(&c.Processing.EnableModeration, "ENABLE_MODERATION", "Moderate prompts before generating scripts", false)
+	r.String(&c.Processing.ModerationBackend, "MODERATION_BACKEND", "Moderation backend: openai or rules", "openai")
+	r.String(&c.Processing.ModerationRules, "MODERATION_RULES_FILE", "Keyword and regex rules used by the rules moderation backend", "moderation/rules.txt")
+	r.Int(&c.Processing.MaxValidationAttempts, "MAX_VALIDATION_ATTEMPTS", "Times a generated script rejected by the security validator is regenerated before giving up", 2)
+	r.Int(&c.Processing.MaxRepairAttempts, "MAX_REPAIR_ATTEMPTS", "Max attempts to let the LLM fix generated scripts that fail to compile (0 disables)", 0)
+	r.Duration(&c.Processing.VideoCacheTTL, "VIDEO_CACHE_TTL", "How long a rendered video is reused for an identical generated script in the same session (0 disables)", 2*time.Minute)
+	r.Int(&c.Processing.ConversationTurns, "CONVERSATION_TURNS", "Number of prompt/response exchanges kept per session for refinement", 5)
 	r.String(&c.Processing.FeaturesFlag, "FEATURES", "Comma-separated list of features to enable", "")
 }
 
+func (c *Config) registerLLMConfig(r *Register) {
+	r.String(&c.LLM.fallbacksList, "LLM_FALLBACKS", "Semicolon-separated fallback chains, e.g. gpt-4o->grok-2-latest->gpt-4o-mini", "")
+	r.Int(&c.LLM.BreakerThreshold, "LLM_BREAKER_THRESHOLD", "Consecutive failures before a model's circuit breaker opens, 0 disables it", 5)
+	r.Duration(&c.LLM.BreakerCooldown, "LLM_BREAKER_COOLDOWN", "Time a model's circuit breaker stays open", 30*time.Second)
+	r.Int(&c.LLM.RetryAttempts, "LLM_RETRY_ATTEMPTS", "Attempts per provider call including the first (1 disables retries)", 3)
+	r.Duration(&c.LLM.RetryBaseDelay, "LLM_RETRY_BASE_DELAY", "Delay before the first retry, doubled for each further retry", 500*time.Millisecond)
+	r.Duration(&c.LLM.RetryMaxDelay, "LLM_RETRY_MAX_DELAY", "Maximum delay between retries, including Retry-After", 10*time.Second)
+	r.Duration(&c.LLM.AttemptTimeout, "LLM_ATTEMPT_TIMEOUT", "Deadline for a single provider call", 90*time.Second)
+	r.Duration(&c.LLM.RequestTimeout, "LLM_REQUEST_TIMEOUT", "Deadline for a whole generation including retries and fallbacks", 3*time.Minute)
+	r.Int(&c.LLM.FewShotExamples, "LLM_FEW_SHOT_EXAMPLES", "Number of curated example scripts sent with each request (0 disables)", 2)
+	r.Int(&c.LLM.MaxCandidates, "LLM_MAX_CANDIDATES", "Maximum number of candidate scripts a generate request may ask for", 4)
+	r.String(&c.LLM.pricesList, "LLM_PRICES", "Semicolon-separated USD prices per million prompt/completion tokens, e.g. gpt-4o=2.5/10", defaultPrices)
+	r.Float(&c.LLM.SessionBudget, "LLM_SESSION_BUDGET", "Maximum USD spent on a session (0 for no limit)", 0)
+	r.Float(&c.LLM.DailyBudget, "LLM_DAILY_BUDGET", "Maximum USD spent per UTC day across all sessions (0 for no limit)", 0)
+	r.String(&c.LLM.Mode, "LLM_MODE", "How providers are reached: live, fake (fixtures), record or replay (cassettes)", "live")
+	r.String(&c.LLM.FakeFixtures, "LLM_FAKE_FIXTURES", "Fixture file used by the fake provider", "fixtures/fake.json")
+	r.String(&c.LLM.CassetteDir, "LLM_CASSETTE_DIR", "Directory of recorded provider cassettes", "cassettes")
+	r.String(&c.LLM.Cache, "LLM_CACHE", "Response cache backend: memory, file or none", "memory")
+	r.String(&c.LLM.CacheDir, "LLM_CACHE_DIR", "Directory of the file response cache", "cache/llm")
+	r.Int(&c.LLM.CacheSize, "LLM_CACHE_SIZE", "Maximum number of cached responses", 1000)
+	r.Duration(&c.LLM.CacheTTL, "LLM_CACHE_TTL", "How long a cached response is reused", 24*time.Hour)
+	r.Bool(&c.LLM.Discovery, "LLM_DISCOVERY", "Discover OpenAI, xAI and compatible provider models from their /v1/models endpoints", false)
+	r.Duration(&c.LLM.DiscoveryInterval, "LLM_DISCOVERY_INTERVAL", "How often discovered models are refreshed (0 lists them at startup only)", time.Hour)
+	r.String(&c.LLM.OpenAIModels.allowList, "OPENAI_MODELS_ALLOW", "Comma-separated patterns of discovered OpenAI models to register", "gpt-4o*,gpt-4.1*")
+	r.String(&c.LLM.OpenAIModels.denyList, "OPENAI_MODELS_DENY", "Comma-separated patterns of discovered OpenAI models to skip", "*audio*,*realtime*,*search*,*transcribe*,*tts*")
+	r.String(&c.LLM.XAIModels.allowList, "XAI_MODELS_ALLOW", "Comma-separated patterns of discovered xAI models to register", "grok-*")
+	r.String(&c.LLM.XAIModels.denyList, "XAI_MODELS_DENY", "Comma-separated patterns of discovered xAI models to skip", "*image*,*vision*")
+	r.String(&c.LLM.PromptsDir, "PROMPTS_DIR", "Directory of system prompt templates named <name>@<version>.tmpl, reloaded on SIGHUP", "")
+	r.String(&c.LLM.PromptDefault, "PROMPT_DEFAULT", "System prompt template used by default, e.g. manim@v2", "")
+	r.String(&c.LLM.promptModelsList, "PROMPT_MODELS", "Semicolon-separated per-model prompt templates, e.g. gpt-4o=manim@v2;grok-2-latest=default@v2", "")
+}
+
 func (c *Config) registerAPIKeys(r *Register) {
 	// OpenAI
 	r.String(&c.OpenAI.Key, "OPENAI_API_KEY", "OpenAI API key", "")
@@ -98,10 +239,66 @@ func (c *Config) registerAPIKeys(r *Register) {
 	r.String(&c.XAI.Key, "XAI_API_KEY", "XAI API key", "")
 	r.String(&c.XAI.keyFile, "XAI_API_KEY_FILE", "Path to Docker secret file containing XAI key", "")
 	r.String(&c.XAI.keySSMPath, "XAI_API_KEY_SSM_PATH", "AWS SSM Parameter Store path for XAI key", "")
+
+	// Anthropic
+	r.String(&c.Anthropic.Key, "ANTHROPIC_API_KEY", "Anthropic API key", "")
+	r.String(&c.Anthropic.keyFile, "ANTHROPIC_API_KEY_FILE", "Path to Docker secret file containing Anthropic key", "")
+	r.String(&c.Anthropic.keySSMPath, "ANTHROPIC_API_KEY_SSM_PATH", "AWS SSM Parameter Store path for Anthropic key", "")
+}
+
+func (c *Config) registerCompatProviders(r *Register) {
+	r.String(&c.compatProviders, "LLM_COMPAT_PROVIDERS", "Comma-separated names of OpenAI-compatible providers (configured from the environment)", "")
+}
+
+// loadCompatProviders reads the settings of the providers listed in
+// LLM_COMPAT_PROVIDERS from their <NAME>_* environment variables. It runs
+// after the flags are parsed, so the list may come from the flag too.
+func (c *Config) loadCompatProviders() {
+	seen := make(map[string]bool)
+	for _, name := range splitList(c.compatProviders) {
+		prefix := envPrefix(name)
+		if seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		c.Compat = append(c.Compat, CompatProviderConfig{
+			Name:       name,
+			BaseURL:    os.Getenv(prefix + "_BASE_URL"),
+			PathPrefix: os.Getenv(prefix + "_PATH_PREFIX"),
+			Models:     splitList(os.Getenv(prefix + "_MODELS")),
+			APIKey: APIKeyConfig{
+				Key:        os.Getenv(prefix + "_API_KEY"),
+				keyFile:    os.Getenv(prefix + "_API_KEY_FILE"),
+				keySSMPath: os.Getenv(prefix + "_API_KEY_SSM_PATH"),
+			},
+			Discovered: ModelPatterns{
+				allowList: os.Getenv(prefix + "_MODELS_ALLOW"),
+				denyList:  os.Getenv(prefix + "_MODELS_DENY"),
+			},
+		})
+	}
 }
 
 func (c *Config) registerWorkerConfig(r *Register) {
 	r.String(&c.Worker.BaseDir, "WORKER_DIR", "Directory for worker temporary files", os.TempDir())
+	r.String(&c.Worker.SubtitlesFormat, "SUBTITLES_FORMAT", "Format of the subtitles made from narration: vtt or srt", "vtt")
+	r.String(&c.Worker.TTSEngine, "TTS_ENGINE", "Engine reading the narration into the video: none, espeak-ng or piper", "none")
+	r.String(&c.Worker.TTSVoice, "TTS_VOICE", "espeak-ng voice name, or path of the piper voice model", "")
+}
+
+func (c *Config) registerHistoryConfig(r *Register) {
+	r.String(&c.History.Store, "HISTORY_STORE", "Where the job history of sessions is kept: memory, sqlite or none", "memory")
+	r.String(&c.History.Path, "HISTORY_DB", "SQLite database file of the job history, required by the sqlite store", "")
+}
+
+func (c *Config) registerSessionConfig(r *Register) {
+	r.String(&c.Session.Store, "SESSION_STORE", "Where user sessions are kept: memory, sqlite or redis", "memory")
+	r.String(&c.Session.Path, "SESSION_DB", "SQLite database file of the sqlite session store", "")
+	r.String(&c.Session.RedisURL, "SESSION_REDIS_URL", "URL of the Redis-compatible server of the redis session store, e.g. redis://:password@localhost:6379/0", "")
+	r.Duration(&c.Session.Lifetime, "SESSION_LIFETIME", "Absolute lifetime of a session", 24*time.Hour)
+	r.Duration(&c.Session.IdleTimeout, "SESSION_IDLE_TIMEOUT", "Sessions unused for this long expire before their lifetime (0 disables)", 0)
+	r.String(&c.Session.CookieName, "SESSION_COOKIE_NAME", "Name of the session cookie", "MANIMATIC_SS")
+	r.String(&c.Session.CookieDomain, "SESSION_COOKIE_DOMAIN", "Domain of the session cookie, e.g. .adelh.dev to share it with subdomains (empty for the host only)", "")
 }
 
 func LoadConfig() (*Config, error) {
@@ -113,11 +310,43 @@ func LoadConfig() (*Config, error) {
 	config.registerAWSConfig(r)
 	config.registerLoggingConfig(r)
 	config.registerProcessingConfig(r)
+	config.registerLLMConfig(r)
 	config.registerAPIKeys(r)
+	config.registerCompatProviders(r)
 	config.registerWorkerConfig(r)
+	config.registerHistoryConfig(r)
+	config.registerSessionConfig(r)
 
 	flag.Parse()
 
+	config.loadCompatProviders()
+	patterns := []*ModelPatterns{&config.LLM.OpenAIModels, &config.LLM.XAIModels}
+	for i := range config.Compat {
+		patterns = append(patterns, &config.Compat[i].Discovered)
+	}
+	for _, p := range patterns {
+		p.Allow = splitList(p.allowList)
+		p.Deny = splitList(p.denyList)
+	}
+
+	fallbacks, err := parseFallbacks(config.LLM.fallbacksList)
+	if err != nil {
+		return nil, err
+	}
+	config.LLM.Fallbacks = fallbacks
+
+	promptModels, err := parsePromptModels(config.LLM.promptModelsList)
+	if err != nil {
+		return nil, err
+	}
+	config.LLM.PromptModels = promptModels
+
+	prices, err := parsePrices(config.LLM.pricesList)
+	if err != nil {
+		return nil, err
+	}
+	config.LLM.Prices = prices
+
 	// Load API keys
 	if err := config.loadAPIKeys(); err != nil {
 		fmt.Println(err.Error())
@@ -160,7 +389,20 @@ func (c *Config) loadAPIKeys() error {
 		loadErrors = append(loadErrors, err)
 	}
 
-	if !c.OpenAI.IsSet && !c.XAI.IsSet {
+	if err := c.loadKey(&c.Anthropic, "Anthropic"); err != nil {
+		loadErrors = append(loadErrors, err)
+	}
+
+	// Compatible providers such as a local Ollama may not need a key at all
+	for i := range c.Compat {
+		if err := c.loadKey(&c.Compat[i].APIKey, c.Compat[i].Name); err != nil {
+			loadErrors = append(loadErrors, err)
+		}
+	}
+
+	// Fake and replayed providers don't call the real APIs
+	offline := c.LLM.Mode == "fake" || c.LLM.Mode == "replay"
+	if !offline && !c.OpenAI.IsSet && !c.XAI.IsSet && !c.Anthropic.IsSet && len(c.Compat) == 0 {
 		return fmt.Errorf("no valid API keys provided. Errors: %v", loadErrors)
 	}
 
@@ -204,6 +446,76 @@ func (c *Config) validate() error {
 	if c.Processing.MaxConcurrency <= 0 || c.Processing.MaxConcurrency > 20 {
 		c.Processing.MaxConcurrency = runtime.NumCPU()
 	}
+	if c.Processing.MaxRepairAttempts < 0 {
+		c.Processing.MaxRepairAttempts = 0
+	}
+	if c.Processing.ConversationTurns < 0 {
+		c.Processing.ConversationTurns = 0
+	}
+	if c.Processing.MaxValidationAttempts < 0 {
+		c.Processing.MaxValidationAttempts = 0
+	}
+
+	// LLM validation
+	if c.LLM.MaxCandidates < 1 {
+		c.LLM.MaxCandidates = 1
+	}
+	switch c.LLM.Mode {
+	case "live", "fake", "record", "replay":
+	default:
+		return fmt.Errorf("invalid LLM mode %q: expected live, fake, record or replay", c.LLM.Mode)
+	}
+
+	// Worker validation
+	switch c.Worker.SubtitlesFormat {
+	case "vtt", "srt":
+	default:
+		return fmt.Errorf("invalid subtitles format %q: expected vtt or srt", c.Worker.SubtitlesFormat)
+	}
+	switch c.Worker.TTSEngine {
+	case "none", "espeak-ng":
+	case "piper":
+		if c.Worker.TTSVoice == "" {
+			return fmt.Errorf("TTS_VOICE must be the path of a voice model when TTS_ENGINE is piper")
+		}
+	default:
+		return fmt.Errorf("invalid TTS engine %q: expected none, espeak-ng or piper", c.Worker.TTSEngine)
+	}
+
+	// History validation
+	switch c.History.Store {
+	case "sqlite":
+		if c.History.Path == "" {
+			return fmt.Errorf("HISTORY_DB is required when HISTORY_STORE is sqlite")
+		}
+	case "memory", "none":
+	default:
+		return fmt.Errorf("invalid history store %q: expected sqlite, memory or none", c.History.Store)
+	}
+
+	// Session validation
+	switch c.Session.Store {
+	case "sqlite":
+		if c.Session.Path == "" {
+			return fmt.Errorf("SESSION_DB is required when SESSION_STORE is sqlite")
+		}
+	case "redis":
+		if c.Session.RedisURL == "" {
+			return fmt.Errorf("SESSION_REDIS_URL is required when SESSION_STORE is redis")
+		}
+	case "memory":
+	default:
+		return fmt.Errorf("invalid session store %q: expected memory, sqlite or redis", c.Session.Store)
+	}
+	if c.Session.Lifetime <= 0 {
+		return fmt.Errorf("invalid session lifetime %s: must be positive", c.Session.Lifetime)
+	}
+	if c.Session.IdleTimeout < 0 {
+		c.Session.IdleTimeout = 0
+	}
+	if c.Session.CookieName == "" {
+		return fmt.Errorf("session cookie name is required")
+	}
 
 	// AWS validation
 	if c.AWS.TaskQueueURL == "" {
@@ -216,6 +528,31 @@ func (c *Config) validate() error {
 		return fmt.Errorf("video bucket name is required")
 	}
 
+	// Compatible provider validation
+	for _, p := range c.Compat {
+		if p.BaseURL == "" {
+			return fmt.Errorf("base URL is required for provider %s", p.Name)
+		}
+		if len(p.Models) == 0 {
+			return fmt.Errorf("at least one model is required for provider %s", p.Name)
+		}
+	}
+
+	// LLM validation
+	// 0 disables the circuit breakers
+	if c.LLM.BreakerThreshold < 0 {
+		c.LLM.BreakerThreshold = 5
+	}
+	if c.LLM.BreakerCooldown <= 0 {
+		c.LLM.BreakerCooldown = 30 * time.Second
+	}
+	if c.LLM.RetryAttempts <= 0 {
+		c.LLM.RetryAttempts = 1
+	}
+	if c.LLM.RequestTimeout <= 0 {
+		c.LLM.RequestTimeout = 3 * time.Minute
+	}
+
 	// Log format validation
 	if c.Logging.Format != "text" && c.Logging.Format != "json" {
 		c.Logging.Format = "json"
@@ -278,24 +615,172 @@ func (c *Config) Debug() string {
 	// Processing Config
 	b.WriteString("⚙️  Processing:\n")
 	b.WriteString(fmt.Sprintf("  ├─ Max Concurrency: %d\n", c.Processing.MaxConcurrency))
-	b.WriteString(fmt.Sprintf("  ├─ Moderation Enabled: %v\n", c.Processing.EnableModeration))
-	b.WriteString(fmt.Sprintf("  └─ Base Dir: %s\n", valueOrEmpty(c.Worker.BaseDir)))
+	b.WriteString(fmt.Sprintf("  ├─ Moderation Enabled: %v (%s)\n", c.Processing.EnableModeration, c.Processing.ModerationBackend))
+	b.WriteString(fmt.Sprintf("  ├─ Max Repair Attempts: %d\n", c.Processing.MaxRepairAttempts))
+	b.WriteString(fmt.Sprintf("  ├─ Max Validation Attempts: %d\n", c.Processing.MaxValidationAttempts))
+	b.WriteString(fmt.Sprintf("  ├─ Conversation Turns: %d\n", c.Processing.ConversationTurns))
+	b.WriteString(fmt.Sprintf("  ├─ Video Cache TTL: %s\n", c.Processing.VideoCacheTTL))
+	b.WriteString(fmt.Sprintf("  ├─ Subtitles: %s, TTS: %s (voice %s)\n", c.Worker.SubtitlesFormat, c.Worker.TTSEngine, valueOrEmpty(c.Worker.TTSVoice)))
+	b.WriteString(fmt.Sprintf("  ├─ Base Dir: %s\n", valueOrEmpty(c.Worker.BaseDir)))
 	b.WriteString(fmt.Sprintf("  └─ Features: %s\n\n", valueOrEmpty(c.Processing.FeaturesFlag)))
 
+	// History Config
+	b.WriteString("🗂️  History:\n")
+	b.WriteString(fmt.Sprintf("  ├─ Store: %s\n", c.History.Store))
+	b.WriteString(fmt.Sprintf("  └─ Database: %s\n\n", valueOrEmpty(c.History.Path)))
+
+	// Session Config
+	b.WriteString("🍪 Sessions:\n")
+	b.WriteString(fmt.Sprintf("  ├─ Store: %s\n", c.Session.Store))
+	b.WriteString(fmt.Sprintf("  ├─ Database: %s\n", valueOrEmpty(c.Session.Path)))
+	b.WriteString(fmt.Sprintf("  ├─ Redis URL Set: %v\n", c.Session.RedisURL != ""))
+	b.WriteString(fmt.Sprintf("  ├─ Lifetime: %s (idle timeout %s)\n", c.Session.Lifetime, c.Session.IdleTimeout))
+	b.WriteString(fmt.Sprintf("  └─ Cookie: %s (domain %s)\n\n", c.Session.CookieName, valueOrEmpty(c.Session.CookieDomain)))
+
+	// LLM Config
+	b.WriteString("🧠 LLM:\n")
+	b.WriteString(fmt.Sprintf("  ├─ Fallbacks: %s\n", valueOrEmpty(c.LLM.fallbacksList)))
+	b.WriteString(fmt.Sprintf("  ├─ Breaker Threshold: %d\n", c.LLM.BreakerThreshold))
+	b.WriteString(fmt.Sprintf("  ├─ Breaker Cooldown: %s\n", c.LLM.BreakerCooldown))
+	b.WriteString(fmt.Sprintf("  ├─ Retry Attempts: %d (base %s, max %s)\n", c.LLM.RetryAttempts, c.LLM.RetryBaseDelay, c.LLM.RetryMaxDelay))
+	b.WriteString(fmt.Sprintf("  ├─ Attempt Timeout: %s\n", c.LLM.AttemptTimeout))
+	b.WriteString(fmt.Sprintf("  ├─ Request Timeout: %s\n", c.LLM.RequestTimeout))
+	b.WriteString(fmt.Sprintf("  ├─ Few-shot Examples: %d\n", c.LLM.FewShotExamples))
+	b.WriteString(fmt.Sprintf("  ├─ Max Candidates: %d\n", c.LLM.MaxCandidates))
+	b.WriteString(fmt.Sprintf("  ├─ Prices: %s\n", valueOrEmpty(c.LLM.pricesList)))
+	b.WriteString(fmt.Sprintf("  ├─ Budgets: $%.2f per session, $%.2f per day (0 = no limit)\n", c.LLM.SessionBudget, c.LLM.DailyBudget))
+	b.WriteString(fmt.Sprintf("  ├─ Mode: %s (fixtures %s, cassettes %s)\n", c.LLM.Mode, c.LLM.FakeFixtures, c.LLM.CassetteDir))
+	b.WriteString(fmt.Sprintf("  ├─ Cache: %s (size %d, ttl %s)\n", c.LLM.Cache, c.LLM.CacheSize, c.LLM.CacheTTL))
+	b.WriteString(fmt.Sprintf("  ├─ Discovery: %v (every %s)\n", c.LLM.Discovery, c.LLM.DiscoveryInterval))
+	b.WriteString(fmt.Sprintf("  │  ├─ OpenAI: allow %s, deny %s\n", valueOrEmpty(c.LLM.OpenAIModels.allowList), valueOrEmpty(c.LLM.OpenAIModels.denyList)))
+	b.WriteString(fmt.Sprintf("  │  └─ xAI: allow %s, deny %s\n", valueOrEmpty(c.LLM.XAIModels.allowList), valueOrEmpty(c.LLM.XAIModels.denyList)))
+	b.WriteString(fmt.Sprintf("  ├─ Prompts Dir: %s\n", valueOrEmpty(c.LLM.PromptsDir)))
+	b.WriteString(fmt.Sprintf("  ├─ Prompt Default: %s\n", valueOrEmpty(c.LLM.PromptDefault)))
+	b.WriteString(fmt.Sprintf("  └─ Prompt Models: %s\n\n", valueOrEmpty(c.LLM.promptModelsList)))
+
 	// API Keys (safely)
 	b.WriteString("🔑 API Keys:\n")
 	b.WriteString(fmt.Sprintf("  ├─ OpenAI:\n"))
 	b.WriteString(fmt.Sprintf("  │  ├─ Key Set: %v\n", c.OpenAI.IsSet))
 	b.WriteString(fmt.Sprintf("  │  ├─ Key File: %s\n", valueOrEmpty(c.OpenAI.keyFile)))
 	b.WriteString(fmt.Sprintf("  │  └─ SSM Path: %s\n", valueOrEmpty(c.OpenAI.keySSMPath)))
-	b.WriteString(fmt.Sprintf("  └─ XAI:\n"))
-	b.WriteString(fmt.Sprintf("     ├─ Key Set: %v\n", c.XAI.IsSet))
-	b.WriteString(fmt.Sprintf("     ├─ Key File: %s\n", valueOrEmpty(c.XAI.keyFile)))
-	b.WriteString(fmt.Sprintf("     └─ SSM Path: %s\n", valueOrEmpty(c.XAI.keySSMPath)))
+	b.WriteString(fmt.Sprintf("  ├─ XAI:\n"))
+	b.WriteString(fmt.Sprintf("  │  ├─ Key Set: %v\n", c.XAI.IsSet))
+	b.WriteString(fmt.Sprintf("  │  ├─ Key File: %s\n", valueOrEmpty(c.XAI.keyFile)))
+	b.WriteString(fmt.Sprintf("  │  └─ SSM Path: %s\n", valueOrEmpty(c.XAI.keySSMPath)))
+	b.WriteString(fmt.Sprintf("  └─ Anthropic:\n"))
+	b.WriteString(fmt.Sprintf("     ├─ Key Set: %v\n", c.Anthropic.IsSet))
+	b.WriteString(fmt.Sprintf("     ├─ Key File: %s\n", valueOrEmpty(c.Anthropic.keyFile)))
+	b.WriteString(fmt.Sprintf("     └─ SSM Path: %s\n", valueOrEmpty(c.Anthropic.keySSMPath)))
+
+	// Compatible providers
+	if len(c.Compat) > 0 {
+		b.WriteString("\n🔌 Compatible Providers:\n")
+		for i, p := range c.Compat {
+			branch, indent := "├─", "│  "
+			if i == len(c.Compat)-1 {
+				branch, indent = "└─", "   "
+			}
+			b.WriteString(fmt.Sprintf("  %s %s:\n", branch, p.Name))
+			b.WriteString(fmt.Sprintf("  %s├─ Base URL: %s\n", indent, p.BaseURL))
+			b.WriteString(fmt.Sprintf("  %s├─ Path Prefix: %s\n", indent, valueOrEmpty(p.PathPrefix)))
+			b.WriteString(fmt.Sprintf("  %s├─ Models: %s\n", indent, strings.Join(p.Models, ", ")))
+			b.WriteString(fmt.Sprintf("  %s├─ Discovery: allow %s, deny %s\n", indent, valueOrEmpty(p.Discovered.allowList), valueOrEmpty(p.Discovered.denyList)))
+			b.WriteString(fmt.Sprintf("  %s└─ Key Set: %v\n", indent, p.APIKey.IsSet))
+		}
+	}
 
 	return b.String()
 }
 
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseFallbacks parses chains like "a->b->c;d->e" into a map from the first
+// model of each chain to the models tried after it.
+func parseFallbacks(s string) (map[string][]string, error) {
+	fallbacks := make(map[string][]string)
+	for _, chain := range strings.Split(s, ";") {
+		if strings.TrimSpace(chain) == "" {
+			continue
+		}
+		var models []string
+		for _, m := range strings.Split(chain, "->") {
+			if m = strings.TrimSpace(m); m != "" {
+				models = append(models, m)
+			}
+		}
+		if len(models) < 2 {
+			return nil, fmt.Errorf("invalid fallback chain %q: needs at least two models", chain)
+		}
+		if _, exists := fallbacks[models[0]]; exists {
+			return nil, fmt.Errorf("duplicate fallback chain for model %s", models[0])
+		}
+		fallbacks[models[0]] = models[1:]
+	}
+	return fallbacks, nil
+}
+
+// parsePromptModels parses assignments like "gpt-4o=manim@v2;grok-2-latest=default@v2"
+func parsePromptModels(s string) (map[string]string, error) {
+	models := make(map[string]string)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		model, id, ok := strings.Cut(pair, "=")
+		model, id = strings.TrimSpace(model), strings.TrimSpace(id)
+		if !ok || model == "" || id == "" {
+			return nil, fmt.Errorf("invalid prompt assignment %q: expected model=name@version", pair)
+		}
+		models[model] = id
+	}
+	return models, nil
+}
+
+// defaultPrices are the list prices of the built-in models
+const defaultPrices = "gpt-4o=2.5/10;gpt-4o-mini=0.15/0.6;grok-2-latest=2/10;" +
+	"claude-3-5-sonnet-latest=3/15;claude-3-5-haiku-latest=0.8/4"
+
+// parsePrices parses prices like "gpt-4o=2.5/10;gpt-4o-mini=0.15/0.6" given
+// in USD per million prompt/completion tokens.
+func parsePrices(s string) (map[string]Price, error) {
+	prices := make(map[string]Price)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		model, price, ok := strings.Cut(entry, "=")
+		prompt, completion, ok2 := strings.Cut(price, "/")
+		if !ok || !ok2 || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("invalid price %q: expected model=prompt/completion", entry)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(prompt), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt price for %s: %w", model, err)
+		}
+		c, err := strconv.ParseFloat(strings.TrimSpace(completion), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid completion price for %s: %w", model, err)
+		}
+		prices[strings.TrimSpace(model)] = Price{Prompt: p, Completion: c}
+	}
+	return prices, nil
+}
+
+// envPrefix turns a provider name into an environment variable prefix
+func envPrefix(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(name))
+}
+
 // valueOrEmpty returns "<not set>" for empty strings
 func valueOrEmpty(s string) string {
 	if s == "" {
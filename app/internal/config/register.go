@@ -5,12 +5,15 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Register struct {
 	stringVars []*string
 	intVars    []*int
 	boolVars   []*bool
+	durVars    []*time.Duration
+	floatVars  []*float64
 }
 
 func (r *Register) String(ptr *string, name, usage string, defValue string) {
@@ -43,3 +46,25 @@ func (r *Register) Bool(ptr *bool, name, usage string, defValue bool) {
 	flag.BoolVar(ptr, strings.ToLower(strings.ReplaceAll(name, "_", "-")), *ptr, usage)
 	r.boolVars = append(r.boolVars, ptr)
 }
+
+func (r *Register) Duration(ptr *time.Duration, name, usage string, defValue time.Duration) {
+	*ptr = defValue
+	if envVal := os.Getenv(name); envVal != "" {
+		if val, err := time.ParseDuration(envVal); err == nil {
+			*ptr = val
+		}
+	}
+	flag.DurationVar(ptr, strings.ToLower(strings.ReplaceAll(name, "_", "-")), *ptr, usage)
+	r.durVars = append(r.durVars, ptr)
+}
+
+func (r *Register) Float(ptr *float64, name, usage string, defValue float64) {
+	*ptr = defValue
+	if envVal := os.Getenv(name); envVal != "" {
+		if val, err := strconv.ParseFloat(envVal, 64); err == nil {
+			*ptr = val
+		}
+	}
+	flag.Float64Var(ptr, strings.ToLower(strings.ReplaceAll(name, "_", "-")), *ptr, usage)
+	r.floatVars = append(r.floatVars, ptr)
+}
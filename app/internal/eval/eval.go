@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"manimatic/internal/llm"
+	"manimatic/internal/worker/manimexec"
+	"manimatic/internal/worker/manimexec/security"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Compiler renders a script, as manimexec.Executor does.
+type Compiler interface {
+	ExecuteScript(ctx context.Context, script string, sessionID string) (*manimexec.ExecutionResult, error)
+}
+
+// Runner runs cases against models.
+type Runner struct {
+	Service     *llm.Service
+	Validator   *security.Validator
+	Compiler    Compiler      // Compiles the scripts that pass validation, nil to skip compiling
+	Params      llm.Params    // Generation parameters of every call
+	Timeout     time.Duration // Limit of each generation, none if 0
+	Concurrency int           // Cases run at once, 1 if unset
+}
+
+// Result is the outcome of a case for a model.
+type Result struct {
+	Case          string    `json:"case"`
+	Model         string    `json:"model"`
+	AnsweredBy    string    `json:"answered_by,omitempty"` // Differs from Model when a fallback answered
+	PromptVersion string    `json:"prompt_version,omitempty"`
+	Passed        bool      `json:"passed"`
+	Error         string    `json:"error,omitempty"` // The generation failed
+	ValidInput    bool      `json:"valid_input"`
+	Violation     string    `json:"violation,omitempty"`     // Why the validator rejected the script
+	CompileError  string    `json:"compile_error,omitempty"` // Category of the compilation failure
+	LatencyMS     float64   `json:"latency_ms"`
+	CompileMS     float64   `json:"compile_ms,omitempty"`
+	Usage         llm.Usage `json:"usage"`
+}
+
+// Run evaluates every case with every model. Results are ordered by model,
+// then by case.
+func (r *Runner) Run(ctx context.Context, cases []Case, models []string) []Result {
+	results := make([]Result, len(models)*len(cases))
+	sem := make(chan struct{}, max(r.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		for j, c := range cases {
+			wg.Add(1)
+			go func(idx int, c Case, model string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[idx] = r.runCase(ctx, c, model)
+			}(i*len(cases)+j, c, model)
+		}
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case, model string) Result {
+	res := Result{Case: c.ID, Model: model}
+
+	genCtx := ctx
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		genCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	resp, err := r.Service.GenerateStream(genCtx, c.Prompt, nil, model, r.Params, func(llm.Chunk) {})
+	res.LatencyMS = milliseconds(time.Since(start))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.AnsweredBy = resp.Model
+	res.PromptVersion = resp.Prompt
+	res.Usage = resp.Usage
+	res.ValidInput = resp.ValidInput && resp.Code != ""
+	if !res.ValidInput || c.ExpectInvalid {
+		res.Passed = res.ValidInput != c.ExpectInvalid
+		return res
+	}
+
+	if err := r.Validator.ValidateScript(resp.Code); err != nil {
+		res.Violation = violation(err)
+		return res
+	}
+	if r.Compiler == nil {
+		res.Passed = true
+		return res
+	}
+
+	start = time.Now()
+	out, err := r.Compiler.ExecuteScript(ctx, resp.Code, "eval")
+	res.CompileMS = milliseconds(time.Since(start))
+	if err != nil {
+		res.CompileError = compileCategory(err)
+		return res
+	}
+	os.RemoveAll(out.WorkingDir)
+	res.Passed = true
+	return res
+}
+
+// violation describes a validation failure without its position, so the
+// same violation counts once however often it occurs.
+func violation(err error) string {
+	var valErr *security.ValidationError
+	if errors.As(err, &valErr) {
+		return valErr.Message
+	}
+	return "syntax error"
+}
+
+// exceptionPattern matches the line naming the Python exception that ended
+// a traceback.
+var exceptionPattern = regexp.MustCompile(`(?m)^\s*([A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt))\b`)
+
+// compileCategory names a compilation failure after the Python exception
+// that caused it, or the kind of the failure when it isn't a traceback.
+func compileCategory(err error) string {
+	var execErr *manimexec.ExecutionError
+	if !errors.As(err, &execErr) {
+		return "Unknown Error"
+	}
+	if execErr.Kind == manimexec.ErrorKindCompilation {
+		if m := exceptionPattern.FindAllStringSubmatch(execErr.Stderr, -1); len(m) > 0 {
+			return m[len(m)-1][1]
+		}
+	}
+	return execErr.Kind.String()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,108 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"manimatic/internal/config"
+	"manimatic/internal/llm"
+	"manimatic/internal/llm/fake"
+	"manimatic/internal/worker/manimexec"
+	"manimatic/internal/worker/manimexec/security"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSuite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suite.jsonl")
+	suite := `{"id": "circle", "prompt": "Draw a circle"}
+
+{"request_id": "user-001", "title": "Title only"}
+{"prompt": "[unrelated] Hello", "expect_invalid": true}
+`
+	if err := os.WriteFile(path, []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadSuite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Case{
+		{ID: "circle", Prompt: "Draw a circle"},
+		{ID: "user-001", Prompt: "Title only"},
+		{ID: "line-4", Prompt: "[unrelated] Hello", ExpectInvalid: true},
+	}
+	if fmt.Sprint(cases) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", cases, want)
+	}
+}
+
+func TestCompileCategory(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&manimexec.ExecutionError{Kind: manimexec.ErrorKindCompilation, Stderr: "Traceback (most recent call last):\n  File \"scene.py\"\nNameError: name 'Circel' is not defined\n"}, "NameError"},
+		{&manimexec.ExecutionError{Kind: manimexec.ErrorKindCompilation, Stderr: "ValueError: first\nDuring handling...\nmanim.utils.exceptions.MultiAnimationRenderException: second"}, "manim.utils.exceptions.MultiAnimationRenderException"},
+		{&manimexec.ExecutionError{Kind: manimexec.ErrorKindCompilation, Stderr: "killed"}, "Compilation Error"},
+		{&manimexec.ExecutionError{Kind: manimexec.ErrorKindTimeout}, "Timeout Error"},
+		{fmt.Errorf("boom"), "Unknown Error"},
+	}
+	for _, tt := range tests {
+		if got := compileCategory(tt.err); got != tt.want {
+			t.Errorf("compileCategory(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestRunWithFakes runs the CI suite the way CI does, with the fake provider
+// and the fake manim binary.
+func TestRunWithFakes(t *testing.T) {
+	fixtures, err := fake.Load("../../fixtures/fake.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := llm.NewService(fixtures.Models[0])
+	fake.RegisterWith(service, fixtures)
+
+	bin, err := filepath.Abs("../../fixtures/bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cfg := &config.Config{}
+	cfg.Worker.BaseDir = t.TempDir()
+
+	cases, err := LoadSuite("../../fixtures/eval.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &Runner{
+		Service:     service,
+		Validator:   security.NewValidator(nil),
+		Compiler:    manimexec.MustNewExecutor(cfg),
+		Concurrency: len(cases),
+	}
+	models := []string{"fake-model"}
+	report := NewReport("eval.jsonl", true, models, runner.Run(context.Background(), cases, models))
+
+	s := report.Models[0]
+	if s.Cases != 6 || s.Passed != 4 || s.Invalid != 1 || s.Errors != 0 {
+		t.Errorf("got %d cases, %d passed, %d invalid, %d errors, want 6, 4, 1, 0", s.Cases, s.Passed, s.Invalid, s.Errors)
+	}
+	if s.Violations["import of 'os' is not allowed"] != 1 || s.CompileErrors["NameError"] != 1 {
+		t.Errorf("got violations %v and compile errors %v", s.Violations, s.CompileErrors)
+	}
+	if s.Usage.CompletionTokens == 0 {
+		t.Error("expected token usage to be reported")
+	}
+
+	var table strings.Builder
+	if err := report.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "66.7%") {
+		t.Errorf("table lacks the pass rate:\n%s", table.String())
+	}
+}
@@ -0,0 +1,153 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"manimatic/internal/llm"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Report is the outcome of a suite run.
+type Report struct {
+	Suite    string         `json:"suite"`
+	Compiled bool           `json:"compiled"` // Scripts were compiled, not only validated
+	Models   []ModelSummary `json:"models"`
+	Results  []Result       `json:"results"`
+}
+
+// ModelSummary aggregates the results of a model.
+type ModelSummary struct {
+	Model          string         `json:"model"`
+	Cases          int            `json:"cases"`
+	Passed         int            `json:"passed"`
+	PassRate       float64        `json:"pass_rate"`
+	Errors         int            `json:"errors"`  // Generations that failed
+	Invalid        int            `json:"invalid"` // Prompts the model flagged as invalid
+	Violations     map[string]int `json:"violations"`
+	CompileErrors  map[string]int `json:"compile_errors"`
+	Latency        Latency        `json:"latency_ms"`
+	CompileLatency Latency        `json:"compile_latency_ms"`
+	Usage          llm.Usage      `json:"usage"`
+}
+
+// Latency summarizes durations in milliseconds.
+type Latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	Max  float64 `json:"max"`
+}
+
+// NewReport summarizes results per model, in the order of models.
+func NewReport(suite string, compiled bool, models []string, results []Result) Report {
+	report := Report{Suite: suite, Compiled: compiled, Results: results}
+	for _, model := range models {
+		s := ModelSummary{
+			Model:         model,
+			Violations:    make(map[string]int),
+			CompileErrors: make(map[string]int),
+		}
+		var latencies, compiles []float64
+		for _, r := range results {
+			if r.Model != model {
+				continue
+			}
+			s.Cases++
+			if r.Passed {
+				s.Passed++
+			}
+			switch {
+			case r.Error != "":
+				s.Errors++
+			case !r.ValidInput:
+				s.Invalid++
+			}
+			if r.Violation != "" {
+				s.Violations[r.Violation]++
+			}
+			if r.CompileError != "" {
+				s.CompileErrors[r.CompileError]++
+			}
+			latencies = append(latencies, r.LatencyMS)
+			if r.CompileMS > 0 {
+				compiles = append(compiles, r.CompileMS)
+			}
+			s.Usage = s.Usage.Add(r.Usage)
+		}
+		if s.Cases > 0 {
+			s.PassRate = float64(s.Passed) / float64(s.Cases)
+		}
+		s.Latency = summarize(latencies)
+		s.CompileLatency = summarize(compiles)
+		report.Models = append(report.Models, s)
+	}
+	return report
+}
+
+func summarize(values []float64) Latency {
+	if len(values) == 0 {
+		return Latency{}
+	}
+	sorted := slices.Sorted(slices.Values(values))
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return Latency{
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(sorted, 0.50),
+		P95:  percentile(sorted, 0.95),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// WriteTable writes the summaries as a table followed by the violations and
+// compile errors of each model.
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tCASES\tPASSED\tPASS RATE\tERRORS\tINVALID\tVIOLATIONS\tCOMPILE ERRORS\tP50 MS\tP95 MS\tCOMPILE P50 MS\tPROMPT TOKENS\tCOMPLETION TOKENS")
+	for _, s := range r.Models {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%d\t%d\t%d\t%d\t%.0f\t%.0f\t%.0f\t%d\t%d\n",
+			s.Model, s.Cases, s.Passed, s.PassRate*100, s.Errors, s.Invalid,
+			total(s.Violations), total(s.CompileErrors),
+			s.Latency.P50, s.Latency.P95, s.CompileLatency.P50,
+			s.Usage.PromptTokens, s.Usage.CompletionTokens)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, s := range r.Models {
+		if len(s.Violations) == 0 && len(s.CompileErrors) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", s.Model)
+		writeCounts(w, "violation", s.Violations)
+		writeCounts(w, "compile error", s.CompileErrors)
+	}
+	return nil
+}
+
+func writeCounts(w io.Writer, label string, counts map[string]int) {
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(w, "  %-14s %3d  %s\n", label, counts[key], strings.TrimSpace(key))
+	}
+}
+
+func total(counts map[string]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+// Package eval runs a suite of prompts through the LLM service and reports
+// how often each model produces a script that passes validation and, when a
+// compiler is given, compiles.
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Case is a prompt of the suite.
+type Case struct {
+	ID            string `json:"id"`
+	Prompt        string `json:"prompt"`
+	ExpectInvalid bool   `json:"expect_invalid"` // The model should flag the prompt as invalid
+}
+
+// suiteLine also accepts the request_id, title and body fields of backlog
+// files such as requests.jsonl.
+type suiteLine struct {
+	Case
+	RequestID string `json:"request_id"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+}
+
+// LoadSuite reads the cases of a JSONL file, one object per line. Blank
+// lines are skipped.
+func LoadSuite(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open suite: %w", err)
+	}
+	defer f.Close()
+
+	var cases []Case
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var line suiteLine
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		c := line.Case
+		c.ID = firstNonEmpty(c.ID, line.RequestID, fmt.Sprintf("line-%d", n))
+		c.Prompt = firstNonEmpty(c.Prompt, line.Body, line.Title)
+		if c.Prompt == "" {
+			return nil, fmt.Errorf("%s:%d: case %s has no prompt", path, n, c.ID)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("suite %s has no cases", path)
+	}
+	return cases, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Wire types for the subset of the Messages API used by the provider.
+
+type message struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"` // A string, or []messagePart when images are attached
+}
+
+type messagePart struct {
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Source *imageSource `json:"source,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type toolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type messagesRequest struct {
+	Model       string      `json:"model"`
+	MaxTokens   int         `json:"max_tokens"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	System      string      `json:"system,omitempty"`
+	Messages    []message   `json:"messages"`
+	Tools       []tool      `json:"tools,omitempty"`
+	ToolChoice  *toolChoice `json:"tool_choice,omitempty"`
+	Stream      bool        `json:"stream,omitempty"`
+}
+
+type contentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type messagesResponse struct {
+	ID         string         `json:"id"`
+	Model      string         `json:"model"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      usage          `json:"usage"`
+}
+
+// streamEvent covers the data payloads of the streaming events we care about.
+type streamEvent struct {
+	Type         string        `json:"type"`
+	Index        int           `json:"index"`
+	ContentBlock *contentBlock `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text,omitempty"`
+		PartialJSON string `json:"partial_json,omitempty"`
+	} `json:"delta,omitempty"`
+	Message *messagesResponse `json:"message,omitempty"` // message_start
+	Usage   *usage            `json:"usage,omitempty"`   // message_delta, cumulative output tokens
+	Error   *errorDetail      `json:"error,omitempty"`
+}
+
+type errorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Type  string      `json:"type"`
+	Error errorDetail `json:"error"`
+}
+
+// APIError is returned when the Messages API responds with a non-2xx status
+// or an error event in a stream.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+	retryAfter time.Duration
+}
+
+// statusOverloaded is Anthropic's non-standard status for an overloaded API
+const statusOverloaded = 529
+
+// HTTPStatus implements llm.HTTPError. Errors delivered inside a stream have
+// no status of their own and are mapped from their type.
+func (e *APIError) HTTPStatus() int {
+	if e.StatusCode != 0 {
+		return e.StatusCode
+	}
+	switch e.Type {
+	case "overloaded_error":
+		return statusOverloaded
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "api_error":
+		return http.StatusInternalServerError
+	}
+	return 0
+}
+
+// RetryAfter implements llm.HTTPError.
+func (e *APIError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Type, e.Message)
+}
@@ -0,0 +1,369 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"manimatic/internal/llm"
+	"net/http"
+	"strings"
+)
+
+type Model string
+
+const (
+	Claude35SonnetLatest Model = "claude-3-5-sonnet-latest"
+	Claude35HaikuLatest  Model = "claude-3-5-haiku-latest"
+)
+
+const (
+	defaultBaseURL     = "https://api.anthropic.com"
+	apiVersion         = "2023-06-01"
+	defaultMaxTokens   = 4096
+	toolName           = "manim_script_response"
+	storyboardToolName = "manim_storyboard_response"
+)
+
+var defaultModels = []Model{
+	Claude35SonnetLatest,
+	Claude35HaikuLatest,
+}
+
+// models describes the built-in models. Relative costs blend prompt and
+// completion prices 3:1.
+var models = map[Model]llm.ModelInfo{
+	Claude35SonnetLatest: {DisplayName: "Claude 3.5 Sonnet", Vision: true, RelativeCost: 23},
+	Claude35HaikuLatest:  {DisplayName: "Claude 3.5 Haiku", RelativeCost: 6},
+}
+
+// The Messages API has no JSON schema response format, so the schema is
+// offered as the only tool and the model is forced to call it.
+var responseTool = tool{
+	Name:        toolName,
+	Description: "Return a response containing code, description, warnings, scene_name, valid_input, and narration fields.",
+	InputSchema: llm.ManimSchema,
+}
+
+var storyboardTool = tool{
+	Name:        storyboardToolName,
+	Description: "Return a storyboard containing title, beats, warnings, and valid_input fields.",
+	InputSchema: llm.StoryboardSchema,
+}
+
+type client struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+}
+
+type Option func(*client)
+
+// WithBaseURL overrides the API base URL, e.g. for a proxy or a test server.
+func WithBaseURL(url string) Option {
+	return func(c *client) {
+		c.baseURL = strings.TrimSuffix(url, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for API calls.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *client) {
+		c.httpClient = hc
+	}
+}
+
+type provider struct {
+	client  *client
+	modelID string
+}
+
+func RegisterWith(service *llm.Service, apiKey string, opts ...Option) error {
+	c := &client{
+		httpClient: http.DefaultClient,
+		baseURL:    defaultBaseURL,
+		apiKey:     apiKey,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	for _, model := range defaultModels {
+		p := &provider{
+			client:  c,
+			modelID: string(model),
+		}
+		service.RegisterProvider(p)
+	}
+
+	return nil
+}
+
+func (p *provider) ModelID() string {
+	return p.modelID
+}
+
+// Info describes the model. The Messages API has no seed parameter.
+func (p *provider) Info() llm.ModelInfo {
+	info := models[Model(p.modelID)]
+	info.Provider = "anthropic"
+	info.ContextWindow = 200_000
+	info.MaxOutputTokens = 8_192
+	info.MaxTemperature = 1
+	info.DefaultTemperature = 1
+	info.DefaultMaxTokens = defaultMaxTokens
+	return info
+}
+
+func (p *provider) request(system string, msgs []message, overrides llm.Params, stream bool) messagesRequest {
+	req := messagesRequest{
+		Model:       p.modelID,
+		MaxTokens:   defaultMaxTokens,
+		Temperature: overrides.Temperature,
+		System:      system,
+		Messages:    msgs,
+		Tools:       []tool{responseTool},
+		ToolChoice:  &toolChoice{Type: "tool", Name: toolName},
+		Stream:      stream,
+	}
+	if overrides.MaxTokens > 0 {
+		req.MaxTokens = overrides.MaxTokens
+	}
+	return req
+}
+
+func (p *provider) Generate(ctx context.Context, req llm.Request) (llm.Response, error) {
+	return p.complete(ctx, p.request(req.SystemPrompt(), messages(req.Messages()), req.Params, false))
+}
+
+func (p *provider) Refine(ctx context.Context, req llm.RefineRequest) (llm.Response, error) {
+	return p.complete(ctx, p.request(req.SystemPrompt(), messages(llm.RefineMessages(req)), req.Params, false))
+}
+
+func messages(conversation []llm.Message) []message {
+	msgs := make([]message, 0, len(conversation))
+	for _, m := range conversation {
+		if len(m.Images) == 0 {
+			msgs = append(msgs, message{Role: string(m.Role), Content: m.Content})
+			continue
+		}
+		// Images go before the text, as recommended for the Messages API
+		parts := make([]messagePart, 0, len(m.Images)+1)
+		for _, img := range m.Images {
+			parts = append(parts, messagePart{
+				Type:   "image",
+				Source: &imageSource{Type: "base64", MediaType: img.MediaType, Data: img.Base64()},
+			})
+		}
+		parts = append(parts, messagePart{Type: "text", Text: m.Content})
+		msgs = append(msgs, message{Role: string(m.Role), Content: parts})
+	}
+	return msgs
+}
+
+func (p *provider) complete(ctx context.Context, req messagesRequest) (llm.Response, error) {
+	body, err := p.client.post(ctx, req)
+	if err != nil {
+		return llm.Response{}, fmt.Errorf("anthropic api call failed: %w", err)
+	}
+	defer body.Close()
+
+	var resp messagesResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return llm.Response{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result, err := parseContent(resp.Content)
+	if err != nil {
+		return llm.Response{}, err
+	}
+	result.Usage = llm.Usage{PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens}
+	return result, nil
+}
+
+func (p *provider) GenerateStoryboard(ctx context.Context, req llm.Request) (llm.Storyboard, error) {
+	payload := p.request(req.SystemPrompt(), messages(req.Messages()), req.Params, false)
+	payload.Tools = []tool{storyboardTool}
+	payload.ToolChoice = &toolChoice{Type: "tool", Name: storyboardToolName}
+	body, err := p.client.post(ctx, payload)
+	if err != nil {
+		return llm.Storyboard{}, fmt.Errorf("anthropic api call failed: %w", err)
+	}
+	defer body.Close()
+
+	var resp messagesResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return llm.Storyboard{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, block := range resp.Content {
+		if block.Type != "tool_use" || block.Name != storyboardToolName {
+			continue
+		}
+		var board llm.Storyboard
+		if err := json.Unmarshal(block.Input, &board); err != nil {
+			return llm.Storyboard{}, fmt.Errorf("failed to parse storyboard: %w", err)
+		}
+		board.Usage = llm.Usage{PromptTokens: resp.Usage.InputTokens, CompletionTokens: resp.Usage.OutputTokens}
+		return board, nil
+	}
+	return llm.Storyboard{}, fmt.Errorf("no storyboard returned")
+}
+
+func parseContent(blocks []contentBlock) (llm.Response, error) {
+	var text strings.Builder
+	for _, block := range blocks {
+		switch block.Type {
+		case "tool_use":
+			if block.Name == toolName {
+				return parseToolInput(block.Input)
+			}
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+
+	if text.Len() == 0 {
+		return llm.Response{}, fmt.Errorf("no response content returned")
+	}
+	return parseStrict(text.String())
+}
+
+func (p *provider) GenerateStream(ctx context.Context, req llm.Request, onChunk func(llm.Chunk)) (llm.Response, error) {
+	body, err := p.client.post(ctx, p.request(req.SystemPrompt(), messages(req.Messages()), req.Params, true))
+	if err != nil {
+		return llm.Response{}, fmt.Errorf("anthropic streaming api call failed: %w", err)
+	}
+	defer body.Close()
+
+	var content strings.Builder
+	var usedTool bool
+	var usage llm.Usage
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		var ev streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			return llm.Response{}, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				usage.PromptTokens = ev.Message.Usage.InputTokens
+				usage.CompletionTokens = ev.Message.Usage.OutputTokens
+			}
+		case "message_delta":
+			if ev.Usage != nil {
+				usage.CompletionTokens = ev.Usage.OutputTokens
+			}
+		case "content_block_start":
+			if ev.ContentBlock != nil && ev.ContentBlock.Type == "tool_use" && ev.ContentBlock.Name == toolName {
+				usedTool = true
+			}
+		case "content_block_delta":
+			if ev.Delta == nil {
+				continue
+			}
+			delta := ev.Delta.Text + ev.Delta.PartialJSON
+			if delta == "" {
+				continue
+			}
+			content.WriteString(delta)
+			onChunk(llm.Chunk{Delta: delta, Content: content.String()})
+		case "error":
+			if ev.Error != nil {
+				return llm.Response{}, fmt.Errorf("anthropic stream failed: %w", &APIError{Type: ev.Error.Type, Message: ev.Error.Message})
+			}
+		case "message_stop":
+			var result llm.Response
+			var err error
+			if usedTool {
+				result, err = parseToolInput([]byte(content.String()))
+			} else {
+				result, err = parseStrict(content.String())
+			}
+			if err != nil {
+				return llm.Response{}, err
+			}
+			result.Usage = usage
+			return result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return llm.Response{}, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return llm.Response{}, errors.New("stream ended before message_stop")
+}
+
+func (c *client) post(ctx context.Context, payload messagesRequest) (io.ReadCloser, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Type:       "api_error",
+			Message:    http.StatusText(resp.StatusCode),
+			retryAfter: llm.ParseRetryAfter(resp.Header),
+		}
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && errResp.Error.Message != "" {
+			apiErr.Type = errResp.Error.Type
+			apiErr.Message = errResp.Error.Message
+		}
+		return nil, apiErr
+	}
+
+	return resp.Body, nil
+}
+
+func parseToolInput(input []byte) (llm.Response, error) {
+	var result llm.Response
+	if err := json.Unmarshal(input, &result); err != nil {
+		return llm.Response{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return result, nil
+}
+
+// parseStrict parses a text reply that must consist of exactly one JSON
+// object matching llm.Response and nothing else.
+func parseStrict(text string) (llm.Response, error) {
+	dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(text)))
+	dec.DisallowUnknownFields()
+
+	var result llm.Response
+	if err := dec.Decode(&result); err != nil {
+		return llm.Response{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if dec.More() {
+		return llm.Response{}, errors.New("failed to parse response: unexpected data after JSON object")
+	}
+	return result, nil
+}
@@ -0,0 +1,190 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"manimatic/internal/llm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const script = "from manim import *\nclass Circle(Scene):\n    pass"
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *provider {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	return &provider{
+		client:  &client{httpClient: srv.Client(), baseURL: srv.URL, apiKey: "test-key"},
+		modelID: string(Claude35SonnetLatest),
+	}
+}
+
+func decodeRequest(t *testing.T, r *http.Request) messagesRequest {
+	t.Helper()
+	if r.URL.Path != "/v1/messages" {
+		t.Errorf("unexpected path %s", r.URL.Path)
+	}
+	if r.Header.Get("x-api-key") != "test-key" {
+		t.Errorf("missing api key header")
+	}
+	if r.Header.Get("anthropic-version") != apiVersion {
+		t.Errorf("missing anthropic-version header")
+	}
+	var req messagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		t.Fatalf("failed to decode request: %v", err)
+	}
+	return req
+}
+
+func TestGenerateToolUse(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		if req.ToolChoice == nil || req.ToolChoice.Name != toolName {
+			t.Errorf("expected forced tool choice, got %+v", req.ToolChoice)
+		}
+		if req.System != llm.DefaultSystemPrompt {
+			t.Errorf("expected default system prompt")
+		}
+
+		input, _ := json.Marshal(llm.Response{Code: script, SceneName: "Circle", ValidInput: true})
+		_ = json.NewEncoder(w).Encode(messagesResponse{
+			ID:         "msg_1",
+			StopReason: "tool_use",
+			Content: []contentBlock{
+				{Type: "text", Text: "Here you go."},
+				{Type: "tool_use", Name: toolName, Input: input},
+			},
+			Usage: usage{InputTokens: 120, OutputTokens: 45},
+		})
+	})
+
+	resp, err := p.Generate(context.Background(), llm.Request{Prompt: "draw a circle"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Code != script || resp.SceneName != "Circle" || !resp.ValidInput {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if want := (llm.Usage{PromptTokens: 120, CompletionTokens: 45}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+}
+
+func TestGenerateStrictText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{name: "plain object", text: ` {"code":"x","description":"","warnings":"","scene_name":"A","valid_input":true} `},
+		{name: "trailing prose", text: `{"code":"x","valid_input":true} done`, wantErr: true},
+		{name: "unknown field", text: `{"code":"x","extra":1}`, wantErr: true},
+		{name: "markdown fence", text: "```json\n{\"code\":\"x\"}\n```", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(messagesResponse{
+					Content: []contentBlock{{Type: "text", Text: tt.text}},
+				})
+			})
+			_, err := p.Generate(context.Background(), llm.Request{Prompt: "draw a circle"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIError(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(errorResponse{
+			Type:  "error",
+			Error: errorDetail{Type: "rate_limit_error", Message: "slow down"},
+		})
+	})
+
+	_, err := p.Generate(context.Background(), llm.Request{Prompt: "draw a circle"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Type != "rate_limit_error" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	input, _ := json.Marshal(llm.Response{Code: script, SceneName: "Circle", ValidInput: true})
+	parts := []string{string(input[:10]), string(input[10:30]), string(input[30:])}
+
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if req := decodeRequest(t, r); !req.Stream {
+			t.Errorf("expected stream request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":120,\"output_tokens\":1}}}\n\n")
+		fmt.Fprintf(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"name\":%q,\"input\":{}}}\n\n", toolName)
+		for _, part := range parts {
+			delta, _ := json.Marshal(part)
+			fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":%s}}\n\n", delta)
+		}
+		fmt.Fprint(w, "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":45}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	})
+
+	var chunks []llm.Chunk
+	resp, err := p.GenerateStream(context.Background(), llm.Request{Prompt: "draw a circle"}, func(c llm.Chunk) {
+		chunks = append(chunks, c)
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if resp.Code != script {
+		t.Errorf("unexpected code: %q", resp.Code)
+	}
+	if want := (llm.Usage{PromptTokens: 120, CompletionTokens: 45}); resp.Usage != want {
+		t.Errorf("usage = %+v, want %+v", resp.Usage, want)
+	}
+	if len(chunks) != len(parts) {
+		t.Fatalf("expected %d chunks, got %d", len(parts), len(chunks))
+	}
+	if last := chunks[len(chunks)-1].Content; last != string(input) {
+		t.Errorf("accumulated content mismatch: %s", last)
+	}
+	if !strings.HasPrefix(script, llm.PartialCode(chunks[1].Content)) {
+		t.Errorf("partial code is not a prefix of the final script")
+	}
+}
+
+func TestGenerateWithImages(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		content, _ := json.Marshal(req.Messages[0].Content)
+		// Decoded into maps, so the keys come back sorted
+		want := `[{"source":{"data":"iVBORw==","media_type":"image/png","type":"base64"},"type":"image"},{"text":"animate this","type":"text"}]`
+		if string(content) != want {
+			t.Errorf("content = %s, want %s", content, want)
+		}
+
+		input, _ := json.Marshal(llm.Response{Code: script, ValidInput: true})
+		_ = json.NewEncoder(w).Encode(messagesResponse{
+			Content: []contentBlock{{Type: "tool_use", Name: toolName, Input: input}},
+		})
+	})
+
+	img := llm.Image{MediaType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}
+	if _, err := p.Generate(context.Background(), llm.Request{Prompt: "animate this", Images: []llm.Image{img}}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for a model whose circuit breaker is open.
+var ErrCircuitOpen = errors.New("model temporarily unavailable")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a per-model circuit breaker. After threshold consecutive
+// failures it opens and rejects calls until cooldown has passed, then lets a
+// single trial call through to decide whether to close again.
+type breaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// A trial call is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release abandons a call that neither succeeded nor failed, e.g. because the
+// caller's context was cancelled.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+func (b *breaker) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerClosed || (b.state == breakerOpen && time.Since(b.openedAt) >= b.cooldown)
+}
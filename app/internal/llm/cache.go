@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores generated responses so that repeated requests skip the
+// provider. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(ctx context.Context, key string) (Response, bool)
+	Set(ctx context.Context, key string, resp Response)
+}
+
+// CacheKey identifies a generation by its normalized prompt, the model and
+// the system prompt template used.
+func CacheKey(prompt, model, promptVersion string) string {
+	sum := sha256.Sum256([]byte(NormalizePrompt(prompt) + "\x00" + model + "\x00" + promptVersion))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizePrompt lower-cases the prompt, collapses whitespace and drops
+// trailing punctuation so trivially different prompts share a cache entry.
+func NormalizePrompt(prompt string) string {
+	p := strings.Join(strings.Fields(strings.ToLower(prompt)), " ")
+	return strings.TrimRight(p, ".!?;, ")
+}
+
+// MemoryCache is an in-process LRU cache with a per-entry TTL.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // Most recently used first
+	items      map[string]*list.Element
+	now        func() time.Time
+}
+
+type memoryEntry struct {
+	key     string
+	resp    Response
+	expires time.Time
+}
+
+func NewMemoryCache(maxEntries int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(_ context.Context, key string) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return Response{}, false
+	}
+	entry := el.Value.(*memoryEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return Response{}, false
+	}
+	c.order.MoveToFront(el)
+	return entry.resp, true
+}
+
+func (c *MemoryCache) Set(_ context.Context, key string, resp Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &memoryEntry{key: key, resp: resp, expires: c.now().Add(c.ttl)}
+	if el, ok := c.items[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(entry)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// FileCache persists responses as JSON files in a directory so they survive
+// restarts and can be shared by instances mounting the same volume. Entries
+// expire ttl after they were written and the oldest ones are removed once
+// the cache holds more than maxEntries.
+type FileCache struct {
+	mu         sync.Mutex
+	dir        string
+	maxEntries int
+	ttl        time.Duration
+}
+
+// fileEntry also keeps the fields of Response that are not serialized.
+type fileEntry struct {
+	Response      Response `json:"response"`
+	Model         string   `json:"model"`
+	PromptVersion string   `json:"prompt_version"`
+}
+
+func NewFileCache(dir string, maxEntries int, ttl time.Duration) (*FileCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &FileCache{dir: dir, maxEntries: maxEntries, ttl: ttl}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *FileCache) Get(_ context.Context, key string) (Response, bool) {
+	path := c.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return Response{}, false
+	}
+	if time.Since(info.ModTime()) > c.ttl {
+		_ = os.Remove(path)
+		return Response{}, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Response{}, false
+	}
+	var entry fileEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Response{}, false
+	}
+	resp := entry.Response
+	resp.Model = entry.Model
+	resp.Prompt = entry.PromptVersion
+	return resp, true
+}
+
+func (c *FileCache) Set(_ context.Context, key string, resp Response) {
+	data, err := json.Marshal(fileEntry{Response: resp, Model: resp.Model, PromptVersion: resp.Prompt})
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	c.evict()
+}
+
+// evict removes the oldest entries beyond maxEntries
+func (c *FileCache) evict() {
+	if c.maxEntries <= 0 {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil || len(files) <= c.maxEntries {
+		return
+	}
+
+	modTimes := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			modTimes[f] = info.ModTime()
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return modTimes[files[i]].Before(modTimes[files[j]])
+	})
+	for _, f := range files[:len(files)-c.maxEntries] {
+		_ = os.Remove(f)
+	}
+}
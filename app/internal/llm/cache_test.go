@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheKeyNormalizesPrompt(t *testing.T) {
+	a := CacheKey("Draw a  sine wave.", "gpt-4o", "default@v1")
+	if b := CacheKey(" draw a sine wave", "gpt-4o", "default@v1"); a != b {
+		t.Error("expected equal keys for equivalent prompts")
+	}
+	if b := CacheKey("draw a sine wave", "gpt-4o", "manim@v2"); a == b {
+		t.Error("expected different keys for different prompt versions")
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewMemoryCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", Response{Code: "a"})
+	c.Set(ctx, "b", Response{Code: "b"})
+	c.Get(ctx, "a") // a is now the most recently used
+	c.Set(ctx, "c", Response{Code: "c"})
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	if resp, ok := c.Get(ctx, "a"); !ok || resp.Code != "a" {
+		t.Errorf("Get(a) = %+v, %v", resp, ok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Error("expected expired entry to be dropped")
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewFileCache(t.TempDir(), 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set(ctx, "a", Response{Code: "a", Model: "gpt-4o", Prompt: "manim@v2"})
+	resp, ok := c.Get(ctx, "a")
+	if !ok || resp.Code != "a" || resp.Model != "gpt-4o" || resp.Prompt != "manim@v2" {
+		t.Fatalf("Get(a) = %+v, %v", resp, ok)
+	}
+
+	time.Sleep(10 * time.Millisecond) // Distinct modification times
+	c.Set(ctx, "b", Response{Code: "b"})
+	if _, ok := c.Get(ctx, "a"); ok {
+		t.Error("expected oldest entry to be evicted")
+	}
+}
+
+func TestGenerateCache(t *testing.T) {
+	p := &stubProvider{id: "a"}
+	s := NewService("a")
+	s.RegisterProvider(p)
+	s.SetCache(NewMemoryCache(10, time.Hour))
+
+	// A response is only served again once the caller accepted it
+	rejected, err := s.Generate(context.Background(), "Draw a circle", "")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	accepted, err := s.Generate(context.Background(), "Draw a circle", "")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if p.calls != 2 || rejected.Cached || accepted.Cached {
+		t.Fatalf("expected a response not remembered to be generated again, got %d calls", p.calls)
+	}
+	s.Remember(context.Background(), accepted)
+
+	resp, err := s.Generate(context.Background(), "draw a circle.", "a")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if p.calls != 2 || !resp.Cached || resp.Model != "a" {
+		t.Errorf("expected cached response from a after 2 calls, got %+v after %d calls", resp, p.calls)
+	}
+}
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// maxCandidateSize is the script size above which a candidate is ranked
+// lower. Scripts this long are usually padded or stuck in a loop.
+const maxCandidateSize = 16_000
+
+// ScriptValidator statically checks a generated script. The worker's
+// security.Validator parses the script and enforces the sandbox rules.
+type ScriptValidator interface {
+	ValidateScript(script string) error
+}
+
+// Candidate is one of several responses generated for the same prompt
+// together with its static score.
+type Candidate struct {
+	Response
+	Score    int
+	Problems []string // Why points were deducted, for logging
+}
+
+// SetValidator sets the validator used to rank candidates in
+// GenerateCandidates. Without one only the scene and size checks apply.
+func (s *Service) SetValidator(v ScriptValidator) {
+	s.validator = v
+}
+
+// ScoreCandidate ranks resp without running it: a script that passes
+// validation outweighs one that declares the scene named in SceneName, which
+// outweighs one of a reasonable size. Unusable responses score below zero.
+func ScoreCandidate(resp Response, v ScriptValidator) Candidate {
+	c := Candidate{Response: resp}
+	if !resp.ValidInput || resp.Code == "" {
+		c.Score = -1
+		c.Problems = append(c.Problems, "no script")
+		return c
+	}
+
+	if v == nil {
+		c.Score += 4
+	} else if err := v.ValidateScript(resp.Code); err != nil {
+		c.Problems = append(c.Problems, err.Error())
+	} else {
+		c.Score += 4
+	}
+	if declaresScene(resp.Code, resp.SceneName) {
+		c.Score += 2
+	} else {
+		c.Problems = append(c.Problems, fmt.Sprintf("no Scene subclass named %q", resp.SceneName))
+	}
+	if len(resp.Code) <= maxCandidateSize {
+		c.Score++
+	} else {
+		c.Problems = append(c.Problems, fmt.Sprintf("script is %d bytes", len(resp.Code)))
+	}
+	return c
+}
+
+// sceneClass matches class declarations deriving from one of the Manim scene
+// types, capturing the class name.
+var sceneClass = regexp.MustCompile(`(?m)^class\s+(\w+)\s*\([^)]*Scene[^)]*\)\s*:`)
+
+// declaresScene reports whether script defines a scene class called name.
+func declaresScene(script, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, m := range sceneClass.FindAllStringSubmatch(script, -1) {
+		if m[1] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RankCandidates orders candidates best first. Equal scores prefer the
+// shorter script.
+func RankCandidates(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return len(candidates[i].Code) < len(candidates[j].Code)
+	})
+}
+
+// GenerateCandidates asks model for n responses in parallel and returns the
+// best ranked one along with every candidate, best first. The returned
+// response carries the usage of all calls. Candidates whose call failed are
+// dropped; the call only fails if all of them did.
+func (s *Service) GenerateCandidates(ctx context.Context, prompt string, images []Image, model string, params Params, n int) (Response, []Candidate, error) {
+	key, resp, ok := s.cached(ctx, prompt, images, model, params)
+	if ok {
+		return resp, []Candidate{ScoreCandidate(resp, s.validator)}, nil
+	}
+
+	n = max(n, 1)
+	examples := s.fewShot(prompt)
+	responses := make([]Response, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = s.call(ctx, model, params, func(p Provider, system string, params Params) (Response, error) {
+				if err := s.requireVision(p.ModelID(), images); err != nil {
+					return Response{}, err
+				}
+				return p.Generate(ctx, Request{System: system, Examples: examples, Prompt: prompt, Images: images, Params: params})
+			})
+		}()
+	}
+	wg.Wait()
+
+	var total Usage
+	var lastErr error
+	candidates := make([]Candidate, 0, n)
+	for i, resp := range responses {
+		if errs[i] != nil {
+			lastErr = errs[i]
+			continue
+		}
+		total = total.Add(resp.Usage)
+		candidates = append(candidates, ScoreCandidate(resp, s.validator))
+	}
+	if len(candidates) == 0 {
+		return Response{}, nil, lastErr
+	}
+
+	RankCandidates(candidates)
+	best := s.cacheable(key, model, candidates[0].Response)
+	best.Usage = total
+	return best, candidates, nil
+}
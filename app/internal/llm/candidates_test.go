@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type rejectImports struct{}
+
+func (rejectImports) ValidateScript(script string) error {
+	if strings.Contains(script, "import os") {
+		return errors.New("import of 'os' is not allowed")
+	}
+	return nil
+}
+
+const goodScript = "from manim import *\n\nclass Demo(Scene):\n    def construct(self):\n        self.play(Create(Circle()))\n"
+
+func TestScoreCandidate(t *testing.T) {
+	tests := []struct {
+		name string
+		resp Response
+		want int
+	}{
+		{"valid", Response{Code: goodScript, SceneName: "Demo", ValidInput: true}, 7},
+		{"wrong scene name", Response{Code: goodScript, SceneName: "Other", ValidInput: true}, 5},
+		{"rejected", Response{Code: "import os\n" + goodScript, SceneName: "Demo", ValidInput: true}, 3},
+		{"later scene", Response{Code: "class Base(Scene):\n    pass\n\nclass Demo(MovingCameraScene):\n    pass\n", SceneName: "Demo", ValidInput: true}, 7},
+		{"not a scene", Response{Code: "class Demo(object):\n    pass\n", SceneName: "Demo", ValidInput: true}, 5},
+		{"too long", Response{Code: goodScript + strings.Repeat("#", maxCandidateSize), SceneName: "Demo", ValidInput: true}, 6},
+		{"invalid input", Response{Code: goodScript, SceneName: "Demo"}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ScoreCandidate(tt.resp, rejectImports{})
+			if c.Score != tt.want {
+				t.Errorf("score = %d, want %d (problems: %v)", c.Score, tt.want, c.Problems)
+			}
+		})
+	}
+}
+
+// candidateProvider returns a different response on every call
+type candidateProvider struct {
+	calls     atomic.Int32
+	responses []Response
+}
+
+func (p *candidateProvider) ModelID() string { return "a" }
+
+func (p *candidateProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	i := int(p.calls.Add(1)) - 1
+	if i >= len(p.responses) {
+		return Response{}, errors.New("no more responses")
+	}
+	return p.responses[i], nil
+}
+
+func TestGenerateCandidates(t *testing.T) {
+	p := &candidateProvider{responses: []Response{
+		{Code: "import os\n" + goodScript, SceneName: "Demo", ValidInput: true, Usage: Usage{PromptTokens: 10, CompletionTokens: 5}},
+		{Code: goodScript, SceneName: "Demo", ValidInput: true, Usage: Usage{PromptTokens: 10, CompletionTokens: 4}},
+		{Code: goodScript, SceneName: "Other", ValidInput: true, Usage: Usage{PromptTokens: 10, CompletionTokens: 3}},
+	}}
+	s := NewService("a")
+	s.ConfigureRetry(RetryPolicy{MaxAttempts: 1})
+	s.SetValidator(rejectImports{})
+	s.RegisterProvider(p)
+
+	best, candidates, err := s.GenerateCandidates(context.Background(), "draw a circle", nil, "", Params{}, 4)
+	if err != nil {
+		t.Fatalf("GenerateCandidates failed: %v", err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates after one failed call, got %d", len(candidates))
+	}
+	if best.SceneName != "Demo" || strings.Contains(best.Code, "import os") || best.Model != "a" {
+		t.Errorf("unexpected best candidate %+v", best)
+	}
+	if best.Usage != (Usage{PromptTokens: 30, CompletionTokens: 12}) {
+		t.Errorf("expected usage of all calls, got %+v", best.Usage)
+	}
+	for i := 1; i < len(candidates); i++ {
+		if candidates[i].Score > candidates[i-1].Score {
+			t.Errorf("candidates not ranked: %d before %d", candidates[i-1].Score, candidates[i].Score)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+// Package cassette records HTTP interactions with model providers into files
+// and replays them, so provider clients can be exercised offline.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Mode string
+
+const (
+	ModeRecord Mode = "record" // Forward requests and save the interactions
+	ModeReplay Mode = "replay" // Answer from the cassette without network access
+)
+
+// ErrNoInteraction is returned in replay mode for requests the cassette has
+// no answer for.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches the request")
+
+// Interaction is a recorded request and its response. Request headers are
+// not recorded so credentials never end up in cassettes.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"` // Path and query; the host is ignored so base URLs can change
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type Response struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body"`
+}
+
+// Transport is an http.RoundTripper recording to or replaying from a
+// cassette file.
+type Transport struct {
+	mu           sync.Mutex
+	mode         Mode
+	path         string
+	base         http.RoundTripper
+	interactions []Interaction
+	used         []bool
+}
+
+// New opens the cassette at path. In replay mode the cassette must exist; in
+// record mode it is overwritten by the interactions recorded through base,
+// http.DefaultTransport if nil.
+func New(path string, mode Mode, base http.RoundTripper) (*Transport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{mode: mode, path: path, base: base}
+
+	switch mode {
+	case ModeRecord:
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create cassette directory: %w", err)
+		}
+	case ModeReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &t.interactions); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		// Bodies are saved indented for readability
+		for i, in := range t.interactions {
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, in.Request.Body); err == nil {
+				t.interactions[i].Request.Body = compact.Bytes()
+			}
+		}
+		t.used = make([]bool, len(t.interactions))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+	return t, nil
+}
+
+// Client returns an HTTP client using the transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if t.mode == ModeReplay {
+		return t.replay(req, recorded)
+	}
+	return t.record(req, recorded)
+}
+
+func (t *Transport) record(req *http.Request, recorded Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: recordedHeaders(resp.Header),
+			Body:    string(body),
+		},
+	})
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replay answers with the first unused interaction with the same method,
+// path and body. If the body differs, for example because a prompt changed,
+// the first unused interaction with the same method and path is used.
+func (t *Transport) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match := -1
+	for i, in := range t.interactions {
+		if t.used[i] || in.Request.Method != recorded.Method || in.Request.Path != recorded.Path {
+			continue
+		}
+		if bytes.Equal(in.Request.Body, recorded.Body) {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recorded.Method, recorded.Path)
+	}
+	t.used[match] = true
+
+	in := t.interactions[match].Response
+	header := in.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Status, http.StatusText(in.Status)),
+		StatusCode:    in.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(in.Body))),
+		ContentLength: int64(len(in.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *Transport) save() error {
+	data, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize cassette: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// newRequest captures a request, restoring its body for the real transport.
+// JSON bodies are compacted so formatting differences don't break matching.
+func newRequest(req *http.Request) (Request, error) {
+	r := Request{Method: req.Method, Path: req.URL.RequestURI()}
+	if req.Body == nil || req.Body == http.NoBody {
+		return r, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return Request{}, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		r.Body = compact.Bytes()
+	} else {
+		quoted, _ := json.Marshal(string(body))
+		r.Body = quoted
+	}
+	return r, nil
+}
+
+// recordedHeaders keeps the response headers clients depend on
+func recordedHeaders(h http.Header) http.Header {
+	kept := http.Header{}
+	for _, name := range []string{"Content-Type", "Retry-After", "Retry-After-Ms"} {
+		if v := h.Values(name); len(v) > 0 {
+			kept[name] = v
+		}
+	}
+	return kept
+}
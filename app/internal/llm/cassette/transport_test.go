@@ -0,0 +1,69 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("authorization header not forwarded")
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		io.WriteString(w, `{"echo":`+string(body)+`}`)
+	}))
+	path := filepath.Join(t.TempDir(), "provider.json")
+
+	post := func(client *http.Client, url, body string) (string, error) {
+		req, _ := http.NewRequest(http.MethodPost, url+"/v1/chat", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	recorder, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{`{"n": 1}`, `{"n": 2}`} {
+		if _, err := post(recorder.Client(), srv.URL, body); err != nil {
+			t.Fatalf("recording failed: %v", err)
+		}
+	}
+	srv.Close()
+
+	player, err := New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Matched by body regardless of order and formatting, on another host
+	if got, err := post(player.Client(), "http://replay.invalid", `{"n":2}`); err != nil || got != `{"echo":{"n": 2}}` {
+		t.Errorf("replay = %q, %v", got, err)
+	}
+	// An unknown body falls back to the remaining interaction for the path
+	if got, err := post(player.Client(), "http://replay.invalid", `{"n":3}`); err != nil || got != `{"echo":{"n": 1}}` {
+		t.Errorf("replay = %q, %v", got, err)
+	}
+	if _, err := post(player.Client(), "http://replay.invalid", `{"n":1}`); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction once the cassette is used up, got %v", err)
+	}
+
+	for _, in := range player.interactions {
+		if in.Response.Headers.Get("Set-Cookie") != "" {
+			t.Error("unexpected header recorded")
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Package compat implements providers for any API compatible with the OpenAI
+// chat completions API: OpenAI and xAI themselves, Ollama, vLLM, LM Studio or
+// an internal gateway. Providers differ only by their Options.
+package compat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"manimatic/internal/llm"
+	"net/http"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Options describes an OpenAI-compatible endpoint.
+type Options struct {
+	Name       string       // Provider name reported in model info and errors
+	BaseURL    string       // Base URL of the API, e.g. http://localhost:11434
+	APIKey     string       // Optional bearer token
+	PathPrefix string       // Optional prefix added to request paths, e.g. /v1
+	Models     []string     // Model IDs served by the endpoint
+	HTTPClient *http.Client // Optional client, e.g. with a cassette transport
+
+	// Info describes known models. Models missing from it get the defaults
+	// any compatible endpoint accepts, with unknown context window, vision
+	// and pricing.
+	Info map[string]llm.ModelInfo
+	// MaxCompletionTokens sends the token limit as max_completion_tokens
+	// rather than the deprecated max_tokens, for APIs that require it.
+	MaxCompletionTokens bool
+}
+
+type provider struct {
+	client  *openai.Client
+	opts    *Options
+	modelID string
+}
+
+func pathPrefixMiddleware(prefix string) option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if !strings.HasPrefix(req.URL.Path, prefix) {
+			req.URL.Path = prefix + req.URL.Path
+		}
+		return next(req)
+	}
+}
+
+func newClient(opts Options) (*openai.Client, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("%s: base URL is required", opts.Name)
+	}
+
+	// Always set the key explicitly so the client never falls back to
+	// OPENAI_API_KEY from the environment and leaks it to another endpoint.
+	clientOpts := []option.RequestOption{
+		option.WithAPIKey(opts.APIKey),
+		option.WithBaseURL(opts.BaseURL),
+		// Retries are handled by llm.Service
+		option.WithMaxRetries(0),
+	}
+	if opts.APIKey == "" {
+		clientOpts = append(clientOpts, option.WithHeaderDel("Authorization"))
+	}
+	if prefix := strings.TrimSuffix(opts.PathPrefix, "/"); prefix != "" {
+		if !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+		clientOpts = append(clientOpts, option.WithMiddleware(pathPrefixMiddleware(prefix)))
+	}
+	if opts.HTTPClient != nil {
+		clientOpts = append(clientOpts, option.WithHTTPClient(opts.HTTPClient))
+	}
+	return openai.NewClient(clientOpts...), nil
+}
+
+// RegisterWith registers the models of the endpoint described by opts.
+func RegisterWith(service *llm.Service, opts Options) error {
+	if len(opts.Models) == 0 {
+		return fmt.Errorf("%s: at least one model is required", opts.Name)
+	}
+	client, err := newClient(opts)
+	if err != nil {
+		return err
+	}
+	for _, model := range opts.Models {
+		service.RegisterProvider(&provider{client: client, opts: &opts, modelID: model})
+	}
+	return nil
+}
+
+// NewDiscoverer returns a discoverer of the models listed by the /models
+// endpoint. The models registered by RegisterWith stay registered whatever
+// the endpoint lists.
+func NewDiscoverer(opts Options) (*llm.Discoverer, error) {
+	client, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	list := func(ctx context.Context) ([]string, error) {
+		var ids []string
+		iter := client.Models.ListAutoPaging(ctx)
+		for iter.Next() {
+			ids = append(ids, iter.Current().ID)
+		}
+		return ids, iter.Err()
+	}
+	newProvider := func(modelID string) llm.Provider {
+		return &provider{client: client, opts: &opts, modelID: modelID}
+	}
+	return llm.NewDiscoverer(opts.Name, list, newProvider), nil
+}
+
+func (p *provider) ModelID() string {
+	return p.modelID
+}
+
+func (p *provider) Info() llm.ModelInfo {
+	info := p.opts.Info[p.modelID]
+	info.Provider = p.opts.Name
+	info.Seed = true
+	info.MaxTemperature = 2
+	info.DefaultTemperature = 1
+	// Without a limit the model may use its whole output budget
+	info.DefaultMaxTokens = info.MaxOutputTokens
+	return info
+}
+
+// params builds a chat completion request applying the overrides of a
+// single request.
+func (p *provider) params(msgs []openai.ChatCompletionMessageParamUnion, overrides llm.Params) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Messages:       openai.F(msgs),
+		ResponseFormat: llm.ResponseFormat,
+		Model:          openai.F(p.modelID),
+	}
+	if t := overrides.Temperature; t != nil {
+		params.Temperature = openai.F(*t)
+	}
+	if overrides.MaxTokens > 0 {
+		if p.opts.MaxCompletionTokens {
+			params.MaxCompletionTokens = openai.F(int64(overrides.MaxTokens))
+		} else {
+			params.MaxTokens = openai.F(int64(overrides.MaxTokens))
+		}
+	}
+	if seed := overrides.Seed; seed != nil {
+		params.Seed = openai.F(*seed)
+	}
+	return params
+}
+
+// chatMessages converts a conversation into chat completion messages
+func chatMessages(system string, conversation []llm.Message) []openai.ChatCompletionMessageParamUnion {
+	msgs := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(system)}
+	for _, m := range conversation {
+		switch {
+		case m.Role == llm.RoleAssistant:
+			msgs = append(msgs, openai.AssistantMessage(m.Content))
+		case len(m.Images) > 0:
+			parts := []openai.ChatCompletionContentPartUnionParam{openai.TextPart(m.Content)}
+			for _, img := range m.Images {
+				parts = append(parts, openai.ImagePart(img.DataURL()))
+			}
+			msgs = append(msgs, openai.UserMessageParts(parts...))
+		default:
+			msgs = append(msgs, openai.UserMessage(m.Content))
+		}
+	}
+	return msgs
+}
+
+func (p *provider) Generate(ctx context.Context, req llm.Request) (llm.Response, error) {
+	return p.complete(ctx, p.params(chatMessages(req.SystemPrompt(), req.Messages()), req.Params))
+}
+
+func (p *provider) Refine(ctx context.Context, req llm.RefineRequest) (llm.Response, error) {
+	return p.complete(ctx, p.params(chatMessages(req.SystemPrompt(), llm.RefineMessages(req)), req.Params))
+}
+
+func (p *provider) complete(ctx context.Context, params openai.ChatCompletionNewParams) (llm.Response, error) {
+	resp, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return llm.Response{}, fmt.Errorf("%s api call failed: %w", p.opts.Name, err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return llm.Response{}, fmt.Errorf("no response choices returned")
+	}
+
+	var result llm.Response
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return llm.Response{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	result.Usage = llm.Usage{
+		PromptTokens:     int(resp.Usage.PromptTokens),
+		CompletionTokens: int(resp.Usage.CompletionTokens),
+	}
+
+	return result, nil
+}
+
+func (p *provider) GenerateStoryboard(ctx context.Context, req llm.Request) (llm.Storyboard, error) {
+	params := p.params(chatMessages(req.SystemPrompt(), req.Messages()), req.Params)
+	params.ResponseFormat = llm.StoryboardFormat
+	resp, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return llm.Storyboard{}, fmt.Errorf("%s api call failed: %w", p.opts.Name, err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return llm.Storyboard{}, fmt.Errorf("no response choices returned")
+	}
+
+	var board llm.Storyboard
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &board); err != nil {
+		return llm.Storyboard{}, fmt.Errorf("failed to parse storyboard: %w", err)
+	}
+	board.Usage = llm.Usage{
+		PromptTokens:     int(resp.Usage.PromptTokens),
+		CompletionTokens: int(resp.Usage.CompletionTokens),
+	}
+
+	return board, nil
+}
+
+func (p *provider) GenerateStream(ctx context.Context, req llm.Request, onChunk func(llm.Chunk)) (llm.Response, error) {
+	params := p.params(chatMessages(req.SystemPrompt(), req.Messages()), req.Params)
+	// The last chunk carries the token usage of the whole stream
+	params.StreamOptions = openai.F(openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.F(true)})
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" || len(acc.Choices) == 0 {
+			continue
+		}
+		onChunk(llm.Chunk{
+			Delta:   chunk.Choices[0].Delta.Content,
+			Content: acc.Choices[0].Message.Content,
+		})
+	}
+	if err := stream.Err(); err != nil {
+		return llm.Response{}, fmt.Errorf("%s streaming api call failed: %w", p.opts.Name, err)
+	}
+
+	if len(acc.Choices) == 0 {
+		return llm.Response{}, fmt.Errorf("no response choices returned")
+	}
+
+	var result llm.Response
+	if err := json.Unmarshal([]byte(acc.Choices[0].Message.Content), &result); err != nil {
+		return llm.Response{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	result.Usage = llm.Usage{
+		PromptTokens:     int(acc.Usage.PromptTokens),
+		CompletionTokens: int(acc.Usage.CompletionTokens),
+	}
+
+	return result, nil
+}
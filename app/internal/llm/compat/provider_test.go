@@ -0,0 +1,177 @@
+package compat
+
+import (
+	"context"
+	"encoding/json"
+	"manimatic/internal/llm"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "must-not-leak")
+
+	var gotPath, gotAuth, gotModel string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		gotModel = body.Model
+
+		content, _ := json.Marshal(llm.Response{Code: "from manim import *", SceneName: "A", ValidInput: true})
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":     "chatcmpl-1",
+			"object": "chat.completion",
+			"model":  body.Model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": string(content)},
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	service := llm.NewService("llama3")
+	err := RegisterWith(service, Options{
+		Name:       "ollama",
+		BaseURL:    srv.URL,
+		PathPrefix: "v1/",
+		Models:     []string{"llama3", "qwen"},
+	})
+	if err != nil {
+		t.Fatalf("RegisterWith failed: %v", err)
+	}
+
+	if models := service.AvailableModels(); len(models) != 2 {
+		t.Fatalf("expected 2 registered models, got %v", models)
+	}
+
+	resp, err := service.Generate(context.Background(), "draw a circle", "qwen")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if gotPath != "/v1/chat/completions" {
+		t.Errorf("expected path /v1/chat/completions, got %s", gotPath)
+	}
+	if gotAuth != "" {
+		t.Errorf("expected no Authorization header, got %q", gotAuth)
+	}
+	if gotModel != "qwen" {
+		t.Errorf("expected model qwen, got %s", gotModel)
+	}
+	if resp.Code != "from manim import *" || !resp.ValidInput {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestRegisterWithValidation(t *testing.T) {
+	service := llm.NewService("")
+	if err := RegisterWith(service, Options{Name: "x", Models: []string{"m"}}); err == nil {
+		t.Error("expected error for missing base URL")
+	}
+	if err := RegisterWith(service, Options{Name: "x", BaseURL: "http://localhost"}); err == nil {
+		t.Error("expected error for missing models")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name                string
+		maxCompletionTokens bool
+		wantField           string
+	}{
+		{"max tokens", false, "max_tokens"},
+		{"max completion tokens", true, "max_completion_tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				content, _ := json.Marshal(llm.Response{Code: "from manim import *", ValidInput: true})
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"choices": []map[string]any{{"message": map[string]any{"role": "assistant", "content": string(content)}}},
+				})
+			}))
+			defer srv.Close()
+
+			service := llm.NewService("known")
+			err := RegisterWith(service, Options{
+				Name:                "builtin",
+				BaseURL:             srv.URL,
+				Models:              []string{"known"},
+				Info:                map[string]llm.ModelInfo{"known": {DisplayName: "Known", MaxOutputTokens: 1000}},
+				MaxCompletionTokens: tt.maxCompletionTokens,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, _ := service.ModelInfo("known")
+			if info.Provider != "builtin" || info.DisplayName != "Known" || info.DefaultMaxTokens != 1000 {
+				t.Errorf("unexpected model info %+v", info)
+			}
+			if _, err := service.Refine(context.Background(), llm.RefineRequest{Script: "from manim import *", Instruction: "make it blue", Params: llm.Params{MaxTokens: 500}}, "known"); err != nil {
+				t.Fatal(err)
+			}
+			if body[tt.wantField] != float64(500) {
+				t.Errorf("request body %v has no %s of 500", body, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestNewDiscoverer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data": []map[string]any{
+				{"id": "llama3", "object": "model", "owned_by": "library"},
+				{"id": "qwen2.5-coder", "object": "model", "owned_by": "library"},
+				{"id": "nomic-embed-text", "object": "model", "owned_by": "library"},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	opts := Options{Name: "ollama", BaseURL: srv.URL, PathPrefix: "/v1", Models: []string{"llama3", "mistral"}}
+	service := llm.NewService("llama3")
+	if err := RegisterWith(service, opts); err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDiscoverer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Filter = llm.ModelFilter{Deny: []string{"*embed*"}}
+
+	added, removed, err := d.Sync(context.Background(), service)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	// Configured models stay even if the endpoint doesn't list them
+	if !slices.Equal(added, []string{"qwen2.5-coder"}) || len(removed) != 0 {
+		t.Errorf("Sync() added %v and removed %v, want [qwen2.5-coder] and []", added, removed)
+	}
+	if _, ok := service.ModelInfo("mistral"); !ok {
+		t.Error("expected the configured model to stay registered")
+	}
+	if info, ok := service.ModelInfo("qwen2.5-coder"); !ok || info.Provider != "ollama" {
+		t.Errorf("unexpected info of a discovered model %+v", info)
+	}
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+)
+
+// ModelFilter selects discovered models by their ID using path.Match
+// patterns such as "gpt-4o*". A model is kept if it matches one of the Allow
+// patterns, or Allow is empty, and none of the Deny patterns.
+type ModelFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+func (f ModelFilter) Match(modelID string) bool {
+	for _, pattern := range f.Deny {
+		if ok, _ := path.Match(pattern, modelID); ok {
+			return false
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range f.Allow {
+		if ok, _ := path.Match(pattern, modelID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Discoverer keeps the models of one provider registered in a Service in
+// line with the models its API lists.
+type Discoverer struct {
+	Name   string // Provider name, for logging
+	Filter ModelFilter
+
+	list        func(ctx context.Context) ([]string, error)
+	newProvider func(modelID string) Provider
+
+	mu    sync.Mutex
+	owned map[string]bool // Models registered through this discoverer
+}
+
+// NewDiscoverer returns a discoverer listing models with list and creating
+// their providers with newProvider.
+func NewDiscoverer(name string, list func(ctx context.Context) ([]string, error), newProvider func(modelID string) Provider) *Discoverer {
+	return &Discoverer{
+		Name:        name,
+		list:        list,
+		newProvider: newProvider,
+		owned:       make(map[string]bool),
+	}
+}
+
+// Sync registers the listed models that pass the filter and unregisters the
+// models it registered that are no longer listed or allowed. Models
+// registered statically or by other providers are left alone, and the
+// default model of the service is never removed.
+func (d *Discoverer) Sync(ctx context.Context, s *Service) (added, removed []string, err error) {
+	ids, err := d.list(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: failed to list models: %w", d.Name, err)
+	}
+	sort.Strings(ids)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	listed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !d.Filter.Match(id) {
+			continue
+		}
+		listed[id] = true
+		if d.owned[id] {
+			continue
+		}
+		if _, taken := s.ModelInfo(id); taken {
+			continue
+		}
+		s.RegisterProvider(d.newProvider(id))
+		d.owned[id] = true
+		added = append(added, id)
+	}
+
+	for id := range d.owned {
+		if listed[id] || id == s.DefaultModel() {
+			continue
+		}
+		s.UnregisterProvider(id)
+		delete(d.owned, id)
+		removed = append(removed, id)
+	}
+	sort.Strings(removed)
+	return added, removed, nil
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestModelFilterMatch(t *testing.T) {
+	filter := ModelFilter{
+		Allow: []string{"gpt-4o*", "gpt-4.1*"},
+		Deny:  []string{"*audio*", "*realtime*"},
+	}
+	tests := []struct {
+		filter ModelFilter
+		model  string
+		want   bool
+	}{
+		{filter, "gpt-4o", true},
+		{filter, "gpt-4o-mini", true},
+		{filter, "gpt-4.1-nano", true},
+		{filter, "gpt-4o-audio-preview", false},
+		{filter, "gpt-4o-realtime-preview", false},
+		{filter, "dall-e-3", false},
+		{ModelFilter{}, "anything", true},
+		{ModelFilter{Deny: []string{"*image*"}}, "grok-2-image", false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.model); got != tt.want {
+			t.Errorf("Match(%q) with %+v = %v, want %v", tt.model, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestDiscovererSync(t *testing.T) {
+	s := NewService("a")
+	s.RegisterProvider(&stubProvider{id: "a"})
+	s.RegisterProvider(&stubProvider{id: "b"})
+	s.RegisterProvider(&stubProvider{id: "other"})
+
+	listed := []string{"a", "b", "c", "d", "other", "skip-me"}
+	list := func(ctx context.Context) ([]string, error) { return listed, nil }
+	newProvider := func(id string) Provider { return &stubProvider{id: id} }
+	d := NewDiscoverer("stub", list, newProvider)
+	d.Filter = ModelFilter{Deny: []string{"skip-*", "b"}}
+
+	added, removed, err := d.Sync(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(added, []string{"c", "d"}) || len(removed) != 0 {
+		t.Fatalf("first sync added %v, removed %v, want [c d] and []", added, removed)
+	}
+
+	// Only discovered models are removed: the statically registered ones,
+	// even filtered out, and those of other providers are never touched.
+	listed = []string{"c"}
+	added, removed, err = d.Sync(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 0 || !slices.Equal(removed, []string{"d"}) {
+		t.Fatalf("second sync added %v, removed %v, want [] and [d]", added, removed)
+	}
+	if got := s.AvailableModels(); !slices.Equal(got, []string{"a", "b", "c", "other"}) {
+		t.Errorf("AvailableModels() = %v, want [a b c other]", got)
+	}
+}
+
+func TestRegisterConcurrentWithCalls(t *testing.T) {
+	s := NewService("a")
+	s.RegisterProvider(&stubProvider{id: "a"})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range 100 {
+			id := fmt.Sprintf("m%d", i%5)
+			s.RegisterProvider(&stubProvider{id: id})
+			s.UnregisterProvider(id)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			if _, err := s.Generate(context.Background(), "prompt", "a"); err != nil {
+				t.Error(err)
+				return
+			}
+			s.AvailableModels()
+			s.Models()
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// examplesFS holds the curated few-shot examples: examples.json lists the
+// prompts and the scripts next to it are known to compile with current Manim.
+//
+//go:embed examples
+var examplesFS embed.FS
+
+const examplesManifest = "examples.json"
+
+// Example is a prompt paired with a known-good script.
+type Example struct {
+	Name        string // Script file name without extension
+	Prompt      string
+	Description string
+	SceneName   string
+	Code        string
+}
+
+type exampleEntry struct {
+	Prompt      string `json:"prompt"`
+	Description string `json:"description"`
+	SceneName   string `json:"scene_name"`
+	Script      string `json:"script"` // Path relative to the manifest
+}
+
+// ExampleLibrary selects the examples most similar to a prompt using TF-IDF
+// weighted cosine similarity over the example prompts and descriptions.
+type ExampleLibrary struct {
+	examples []Example
+	vectors  []map[string]float64
+	idf      map[string]float64
+}
+
+// DefaultExamples returns the library shipped with the package.
+func DefaultExamples() (*ExampleLibrary, error) {
+	sub, err := fs.Sub(examplesFS, "examples")
+	if err != nil {
+		return nil, err
+	}
+	return LoadExamples(sub)
+}
+
+// LoadExamples reads examples.json and the scripts it references from fsys.
+func LoadExamples(fsys fs.FS) (*ExampleLibrary, error) {
+	data, err := fs.ReadFile(fsys, examplesManifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read examples: %w", err)
+	}
+	var entries []exampleEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", examplesManifest, err)
+	}
+
+	examples := make([]Example, 0, len(entries))
+	for _, e := range entries {
+		code, err := fs.ReadFile(fsys, e.Script)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read example script: %w", err)
+		}
+		examples = append(examples, Example{
+			Name:        strings.TrimSuffix(path.Base(e.Script), path.Ext(e.Script)),
+			Prompt:      e.Prompt,
+			Description: e.Description,
+			SceneName:   e.SceneName,
+			Code:        string(code),
+		})
+	}
+	return NewExampleLibrary(examples), nil
+}
+
+func NewExampleLibrary(examples []Example) *ExampleLibrary {
+	docs := make([][]string, len(examples))
+	df := make(map[string]int)
+	for i, e := range examples {
+		docs[i] = tokenize(e.Prompt + " " + e.Description)
+		seen := make(map[string]bool)
+		for _, t := range docs[i] {
+			if !seen[t] {
+				seen[t] = true
+				df[t]++
+			}
+		}
+	}
+
+	// Smoothed IDF so that terms present in every example still count a little
+	n := float64(len(examples))
+	idf := make(map[string]float64, len(df))
+	for t, c := range df {
+		idf[t] = math.Log((1+n)/(1+float64(c))) + 1
+	}
+
+	lib := &ExampleLibrary{examples: examples, idf: idf}
+	for _, doc := range docs {
+		lib.vectors = append(lib.vectors, lib.vector(doc))
+	}
+	return lib
+}
+
+// Select returns up to n examples most similar to prompt, best first.
+// Examples sharing no terms with the prompt are never selected and ties are
+// broken by name, so the result only depends on the prompt and the library.
+func (l *ExampleLibrary) Select(prompt string, n int) []Example {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	query := l.vector(tokenize(prompt))
+
+	type scored struct {
+		index int
+		score float64
+	}
+	var candidates []scored
+	for i, v := range l.vectors {
+		if s := cosine(query, v); s > 0 {
+			candidates = append(candidates, scored{i, s})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return l.examples[a.index].Name < l.examples[b.index].Name
+	})
+
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	selected := make([]Example, len(candidates))
+	for i, c := range candidates {
+		selected[i] = l.examples[c.index]
+	}
+	return selected
+}
+
+// ExampleMessages turns examples into few-shot turns: the example prompt as
+// the user turn and the response the model is expected to give as the
+// assistant turn.
+func ExampleMessages(examples []Example) []Message {
+	msgs := make([]Message, 0, 2*len(examples))
+	for _, e := range examples {
+		reply, err := json.Marshal(Response{
+			Code:        e.Code,
+			Description: e.Description,
+			SceneName:   e.SceneName,
+			ValidInput:  true,
+		})
+		if err != nil {
+			continue
+		}
+		msgs = append(msgs,
+			Message{Role: RoleUser, Content: e.Prompt},
+			Message{Role: RoleAssistant, Content: string(reply)},
+		)
+	}
+	return msgs
+}
+
+// vector returns the normalised TF-IDF vector of tokens. Terms unknown to
+// the library are ignored.
+func (l *ExampleLibrary) vector(tokens []string) map[string]float64 {
+	v := make(map[string]float64)
+	for _, t := range tokens {
+		if w, ok := l.idf[t]; ok {
+			v[t] += w
+		}
+	}
+	var norm float64
+	for _, w := range v {
+		norm += w * w
+	}
+	norm = math.Sqrt(norm)
+	for t := range v {
+		v[t] /= norm
+	}
+	return v
+}
+
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot float64
+	for t, w := range a {
+		dot += w * b[t]
+	}
+	return dot
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "to": true,
+	"in": true, "on": true, "with": true, "for": true, "it": true, "its": true,
+	"is": true, "are": true, "be": true, "then": true, "that": true, "this": true,
+	"into": true, "from": true, "by": true, "at": true, "as": true, "each": true,
+	"show": true, "make": true, "create": true, "animate": true, "animation": true,
+	"scene": true, "please": true,
+}
+
+// tokenize splits text into lower-case words, dropping stop words and a
+// trailing plural "s".
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, w := range words {
+		if stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
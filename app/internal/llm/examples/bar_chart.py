@@ -0,0 +1,18 @@
+"""Animate a bar chart growing from zero to its values."""
+from manim import *
+
+
+class GrowingBarChart(Scene):
+    def construct(self):
+        chart = BarChart(
+            values=[0, 0, 0, 0],
+            bar_names=["Q1", "Q2", "Q3", "Q4"],
+            y_range=[0, 10, 2],
+            bar_colors=[BLUE, GREEN, YELLOW, RED],
+        )
+        title = Text("Quarterly sales").scale(0.8).next_to(chart, UP)
+
+        self.play(Create(chart), Write(title))
+        # change_bar_values returns nothing, so animate through .animate
+        self.play(chart.animate.change_bar_values([4, 7, 5, 9]), run_time=2)
+        self.wait()
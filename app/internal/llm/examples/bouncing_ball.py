@@ -0,0 +1,21 @@
+"""Bounce a ball across the floor using an updater driven by a ValueTracker."""
+from manim import *
+
+
+class BouncingBall(Scene):
+    def construct(self):
+        floor = Line(LEFT * 6, RIGHT * 6).shift(DOWN * 2)
+        ball = Dot(radius=0.25, color=ORANGE)
+        time = ValueTracker(0)
+
+        # Position the ball from the tracked time on every frame
+        def bounce(mob):
+            t = time.get_value()
+            height = abs(np.sin(t * PI)) * 3
+            mob.move_to(LEFT * 5 + RIGHT * t * 2.5 + DOWN * 1.75 + UP * height)
+
+        ball.add_updater(bounce)
+        self.add(floor, ball)
+        self.play(time.animate.set_value(4), run_time=4, rate_func=linear)
+        ball.clear_updaters()
+        self.wait()
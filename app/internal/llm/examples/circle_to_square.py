@@ -0,0 +1,13 @@
+"""Transform a blue circle into a red square."""
+from manim import *
+
+
+class CircleToSquare(Scene):
+    def construct(self):
+        circle = Circle(color=BLUE, fill_opacity=0.5)
+        square = Square(color=RED, fill_opacity=0.5)
+
+        # Draw the circle, then morph it into the square
+        self.play(Create(circle))
+        self.play(Transform(circle, square))
+        self.wait()
@@ -0,0 +1,23 @@
+"""Plot a sine and a cosine curve on labelled axes."""
+from manim import *
+
+
+class FunctionGraph(Scene):
+    def construct(self):
+        axes = Axes(
+            x_range=[-PI, PI, PI / 2],
+            y_range=[-1.5, 1.5, 0.5],
+            axis_config={"include_tip": True},
+        )
+        labels = axes.get_axis_labels(x_label="x", y_label="y")
+
+        # Axes.plot replaces the removed get_graph method
+        sine = axes.plot(lambda x: np.sin(x), color=BLUE)
+        cosine = axes.plot(lambda x: np.cos(x), color=RED)
+        sine_label = axes.get_graph_label(sine, label=MathTex(r"\sin(x)"), x_val=PI / 2)
+        cosine_label = axes.get_graph_label(cosine, label=MathTex(r"\cos(x)"), x_val=-PI / 2, direction=DOWN)
+
+        self.play(Create(axes), Write(labels))
+        self.play(Create(sine), Write(sine_label))
+        self.play(Create(cosine), Write(cosine_label))
+        self.wait()
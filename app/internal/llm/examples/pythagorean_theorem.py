@@ -0,0 +1,16 @@
+"""Write the Pythagorean theorem and highlight each term."""
+from manim import *
+
+
+class PythagoreanTheorem(Scene):
+    def construct(self):
+        title = Text("Pythagorean theorem").to_edge(UP)
+        # MathTex splits the formula into separately addressable parts
+        formula = MathTex("a^2", "+", "b^2", "=", "c^2").scale(1.5)
+
+        self.play(Write(title))
+        self.play(Write(formula))
+        for index in (0, 2, 4):
+            self.play(Indicate(formula[index], color=YELLOW))
+        self.play(Circumscribe(formula))
+        self.wait()
@@ -0,0 +1,17 @@
+"""Rotate a cube in 3D while the camera orbits around it."""
+from manim import *
+
+
+class RotatingCube(ThreeDScene):
+    def construct(self):
+        axes = ThreeDAxes()
+        cube = Cube(side_length=2, fill_color=BLUE, fill_opacity=0.7)
+
+        self.set_camera_orientation(phi=70 * DEGREES, theta=30 * DEGREES)
+        self.play(Create(axes), Create(cube))
+
+        # Orbit the camera while the cube spins around the z axis
+        self.begin_ambient_camera_rotation(rate=0.3)
+        self.play(Rotate(cube, angle=2 * PI, axis=OUT), run_time=4)
+        self.stop_ambient_camera_rotation()
+        self.wait()
@@ -0,0 +1,15 @@
+"""Introduce a title, replace it with a subtitle and fade everything out."""
+from manim import *
+
+
+class TextSequence(Scene):
+    def construct(self):
+        title = Text("Hello, Manim!", font_size=72)
+        subtitle = Text("Mathematical animations in Python", font_size=36)
+
+        self.play(Write(title))
+        self.wait()
+        self.play(title.animate.to_edge(UP).scale(0.6))
+        self.play(FadeIn(subtitle, shift=UP))
+        self.wait()
+        self.play(FadeOut(title), FadeOut(subtitle))
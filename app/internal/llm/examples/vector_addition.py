@@ -0,0 +1,26 @@
+"""Show the sum of two vectors on a number plane with the parallelogram rule."""
+from manim import *
+
+
+class VectorAddition(Scene):
+    def construct(self):
+        plane = NumberPlane()
+        v = Vector([2, 1], color=BLUE)
+        w = Vector([1, 2], color=GREEN)
+        total = Vector([3, 3], color=YELLOW)
+
+        v_label = MathTex(r"\vec{v}", color=BLUE).next_to(v.get_end(), RIGHT)
+        w_label = MathTex(r"\vec{w}", color=GREEN).next_to(w.get_end(), LEFT)
+        sum_label = MathTex(r"\vec{v} + \vec{w}", color=YELLOW).next_to(total.get_end(), UR)
+
+        self.play(Create(plane))
+        self.play(GrowArrow(v), Write(v_label))
+        self.play(GrowArrow(w), Write(w_label))
+
+        # Move copies of each vector to the tip of the other
+        self.play(
+            w.copy().animate.shift(v.get_end()),
+            v.copy().animate.shift(w.get_end()),
+        )
+        self.play(GrowArrow(total), Write(sum_label))
+        self.wait()
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestExampleSelection checks the shipped library against a fixture of
+// prompts and the examples they must select. Selection is repeated on fresh
+// libraries to catch any dependence on map iteration order.
+func TestExampleSelection(t *testing.T) {
+	data, err := os.ReadFile("testdata/example_selection.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []struct {
+		Prompt string   `json:"prompt"`
+		Want   []string `json:"want"`
+	}
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+
+	for run := 0; run < 20; run++ {
+		lib, err := DefaultExamples()
+		if err != nil {
+			t.Fatalf("DefaultExamples failed: %v", err)
+		}
+		for _, tt := range cases {
+			var got []string
+			for _, e := range lib.Select(tt.Prompt, 2) {
+				got = append(got, e.Name)
+			}
+			if !slices.Equal(got, tt.Want) {
+				t.Fatalf("run %d: Select(%q) = %v, want %v", run, tt.Prompt, got, tt.Want)
+			}
+		}
+	}
+}
+
+func TestExampleMessages(t *testing.T) {
+	msgs := ExampleMessages([]Example{{Prompt: "draw a circle", Code: "code", SceneName: "Circle"}})
+	if len(msgs) != 2 || msgs[0].Role != RoleUser || msgs[1].Role != RoleAssistant {
+		t.Fatalf("ExampleMessages() = %+v, want a user and an assistant turn", msgs)
+	}
+	var reply Response
+	if err := json.Unmarshal([]byte(msgs[1].Content), &reply); err != nil {
+		t.Fatalf("assistant turn is not a response: %v", err)
+	}
+	if reply.Code != "code" || !reply.ValidInput {
+		t.Errorf("reply = %+v", reply)
+	}
+}
@@ -0,0 +1,267 @@
+// Package fake provides a deterministic provider that answers from fixtures
+// instead of calling a model, for tests and local development.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"manimatic/internal/llm"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixtures is the content of a fixture file.
+type Fixtures struct {
+	Models   []string  `json:"models"`   // Model IDs the fixtures are served under
+	Fixtures []Fixture `json:"fixtures"` // Tried in order, the first match answers
+}
+
+// Fixture scripts the answer to the prompts it matches.
+type Fixture struct {
+	Match    string        `json:"match"`    // Case-insensitive substring of the prompt, empty matches any prompt
+	Times    int           `json:"times"`    // How many calls the fixture answers, 0 for no limit
+	Latency  Duration      `json:"latency"`  // Delay before answering
+	Error    string        `json:"error"`    // Fail with this message instead of responding
+	Status   int           `json:"status"`   // HTTP status reported with Error, decides whether it is retried
+	Chunks   int           `json:"chunks"`   // Number of chunks a streamed response is split into, default 4
+	Response *llm.Response `json:"response"` // The response to return
+
+	// Storyboard is returned when a storyboard is asked for. Fixtures with
+	// only a storyboard don't answer other calls and vice versa.
+	Storyboard *llm.Storyboard `json:"storyboard"`
+}
+
+// answers reports whether f answers storyboard calls or, if storyboard is
+// false, script calls. Fixtures that fail answer both.
+func (f Fixture) answers(storyboard bool) bool {
+	if f.Error != "" || (f.Response == nil && f.Storyboard == nil) {
+		return true
+	}
+	if storyboard {
+		return f.Storyboard != nil
+	}
+	return f.Response != nil
+}
+
+// Duration is a time.Duration read from strings like "250ms".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Error is returned by fixtures with an error. It implements llm.HTTPError
+// so it goes through the same retry and breaker logic as real failures.
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string             { return fmt.Sprintf("fake provider: %s", e.Message) }
+func (e *Error) HTTPStatus() int           { return e.Status }
+func (e *Error) RetryAfter() time.Duration { return 0 }
+
+// ErrNoFixture is returned when no fixture matches the prompt. It is a
+// mistake in the fixtures, not a provider failure, so it is neither retried
+// nor counted by the circuit breaker.
+var ErrNoFixture = errors.New("fake provider: no fixture matches the prompt")
+
+// Load reads a fixture file.
+func Load(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var f Fixtures
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+	if len(f.Models) == 0 {
+		return nil, fmt.Errorf("fixtures must declare at least one model")
+	}
+	return &f, nil
+}
+
+// RegisterWith registers a provider answering from fixtures for each of
+// their models. The providers share the fixtures and their call counts.
+func RegisterWith(service *llm.Service, fixtures *Fixtures) {
+	s := &script{fixtures: fixtures.Fixtures, used: make([]int, len(fixtures.Fixtures))}
+	for _, model := range fixtures.Models {
+		service.RegisterProvider(&Provider{modelID: model, script: s})
+	}
+}
+
+// New returns a provider for model answering from fixtures.
+func New(model string, fixtures ...Fixture) *Provider {
+	return &Provider{modelID: model, script: &script{fixtures: fixtures, used: make([]int, len(fixtures))}}
+}
+
+type script struct {
+	mu       sync.Mutex
+	fixtures []Fixture
+	used     []int
+}
+
+// next returns the first fixture matching prompt that has calls left and
+// answers the kind of call.
+func (s *script) next(prompt string, storyboard bool) (Fixture, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prompt = strings.ToLower(prompt)
+	for i, f := range s.fixtures {
+		if (f.Times > 0 && s.used[i] >= f.Times) || !f.answers(storyboard) {
+			continue
+		}
+		if strings.Contains(prompt, strings.ToLower(f.Match)) {
+			s.used[i]++
+			return f, true
+		}
+	}
+	return Fixture{}, false
+}
+
+type Provider struct {
+	modelID string
+	script  *script
+}
+
+func (p *Provider) ModelID() string {
+	return p.modelID
+}
+
+// Info accepts every parameter and images so requests can be tested against
+// the fake.
+func (p *Provider) Info() llm.ModelInfo {
+	return llm.ModelInfo{
+		DisplayName:        "Fake " + p.modelID,
+		Provider:           "fake",
+		Vision:             true,
+		Seed:               true,
+		MaxTemperature:     2,
+		DefaultTemperature: 1,
+	}
+}
+
+func (p *Provider) Generate(ctx context.Context, req llm.Request) (llm.Response, error) {
+	f, err := p.answer(ctx, req.Prompt, false)
+	if err != nil {
+		return llm.Response{}, err
+	}
+	return response(f, req), nil
+}
+
+// GenerateStream streams the JSON encoding of the response in f.Chunks
+// pieces, spreading the latency over them.
+func (p *Provider) GenerateStream(ctx context.Context, req llm.Request, onChunk func(llm.Chunk)) (llm.Response, error) {
+	f, ok := p.script.next(req.Prompt, false)
+	if !ok {
+		return llm.Response{}, ErrNoFixture
+	}
+	if f.Error != "" || f.Response == nil {
+		if err := sleep(ctx, time.Duration(f.Latency)); err != nil {
+			return llm.Response{}, err
+		}
+		return llm.Response{}, fixtureError(f)
+	}
+
+	content, err := json.Marshal(f.Response)
+	if err != nil {
+		return llm.Response{}, err
+	}
+	chunks := f.Chunks
+	if chunks <= 0 {
+		chunks = 4
+	}
+	size := (len(content) + chunks - 1) / chunks
+	for start := 0; start < len(content); start += size {
+		if err := sleep(ctx, time.Duration(f.Latency)/time.Duration(chunks)); err != nil {
+			return llm.Response{}, err
+		}
+		end := min(start+size, len(content))
+		onChunk(llm.Chunk{Delta: string(content[start:end]), Content: string(content[:end])})
+	}
+	return response(f, req), nil
+}
+
+// GenerateStoryboard answers from the first fixture with a storyboard.
+func (p *Provider) GenerateStoryboard(ctx context.Context, req llm.Request) (llm.Storyboard, error) {
+	f, err := p.answer(ctx, req.Prompt, true)
+	if err != nil {
+		return llm.Storyboard{}, err
+	}
+	board := *f.Storyboard
+	board.Usage = llm.Usage{PromptTokens: (len(req.SystemPrompt()) + len(req.Prompt)) / 4}
+	for _, beat := range board.Beats {
+		board.Usage.CompletionTokens += (len(beat.Description) + len(beat.Narration)) / 4
+	}
+	return board, nil
+}
+
+func (p *Provider) answer(ctx context.Context, prompt string, storyboard bool) (Fixture, error) {
+	f, ok := p.script.next(prompt, storyboard)
+	if !ok {
+		return Fixture{}, ErrNoFixture
+	}
+	if err := sleep(ctx, time.Duration(f.Latency)); err != nil {
+		return Fixture{}, err
+	}
+	if f.Error != "" || (storyboard && f.Storyboard == nil) || (!storyboard && f.Response == nil) {
+		return Fixture{}, fixtureError(f)
+	}
+	return f, nil
+}
+
+func fixtureError(f Fixture) error {
+	status := f.Status
+	if status == 0 {
+		status = 500
+	}
+	message := f.Error
+	if message == "" {
+		message = "fixture has no response"
+	}
+	return &Error{Status: status, Message: message}
+}
+
+// response returns the fixture's response with a rough token count, about
+// four characters per token, so usage accounting can be exercised.
+func response(f Fixture, req llm.Request) llm.Response {
+	resp := *f.Response
+	prompt := len(req.SystemPrompt()) + len(req.Prompt)
+	for _, m := range req.Examples {
+		prompt += len(m.Content)
+	}
+	resp.Usage = llm.Usage{
+		PromptTokens:     prompt / 4,
+		CompletionTokens: (len(resp.Code) + len(resp.Description)) / 4,
+	}
+	return resp
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"manimatic/internal/llm"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProviderFixtures(t *testing.T) {
+	circle := &llm.Response{Code: "circle", ValidInput: true}
+	p := New("fake",
+		Fixture{Match: "Circle", Times: 1, Error: "overloaded", Status: 503},
+		Fixture{Match: "circle", Response: circle},
+	)
+
+	_, err := p.Generate(context.Background(), llm.Request{Prompt: "draw a circle"})
+	var fakeErr *Error
+	if !errors.As(err, &fakeErr) || fakeErr.HTTPStatus() != 503 {
+		t.Fatalf("first call: expected 503 fixture error, got %v", err)
+	}
+	resp, err := p.Generate(context.Background(), llm.Request{Prompt: "draw a circle"})
+	if err != nil || resp.Code != "circle" {
+		t.Fatalf("second call: got %+v, %v", resp, err)
+	}
+	if resp.Usage.PromptTokens == 0 {
+		t.Error("expected estimated prompt tokens")
+	}
+	if _, err := p.Generate(context.Background(), llm.Request{Prompt: "draw a square"}); err != ErrNoFixture {
+		t.Errorf("expected ErrNoFixture, got %v", err)
+	}
+}
+
+func TestProviderThroughService(t *testing.T) {
+	s := llm.NewService("fake")
+	s.ConfigureRetry(llm.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Timeout: time.Second})
+	s.RegisterProvider(New("fake",
+		Fixture{Times: 1, Error: "rate limited", Status: 429},
+		Fixture{Response: &llm.Response{Code: "script", ValidInput: true}, Chunks: 3},
+	))
+
+	var chunks []llm.Chunk
+	resp, err := s.GenerateStream(context.Background(), "anything", nil, "", llm.Params{}, func(c llm.Chunk) {
+		chunks = append(chunks, c)
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if resp.Code != "script" || resp.Model != "fake" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if len(chunks) != 3 || !strings.Contains(chunks[2].Content, `"code":"script"`) {
+		t.Errorf("unexpected chunks %+v", chunks)
+	}
+
+	// Missing fixtures are a mistake of the test, not a failing provider
+	empty := llm.NewService("empty")
+	empty.ConfigureBreakers(1, time.Minute)
+	empty.RegisterProvider(New("empty"))
+	for range 2 {
+		if _, err := empty.Generate(context.Background(), "anything", ""); !errors.Is(err, ErrNoFixture) {
+			t.Fatalf("expected ErrNoFixture, got %v", err)
+		}
+	}
+}
+
+func TestProviderLatencyHonoursContext(t *testing.T) {
+	p := New("fake", Fixture{Latency: Duration(time.Minute), Response: &llm.Response{}})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Generate(ctx, llm.Request{Prompt: "x"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestStoryboardThroughService(t *testing.T) {
+	board := &llm.Storyboard{
+		Title:      "Circle",
+		Beats:      []llm.Beat{{Description: "Draw a circle", Narration: "A circle.", Duration: 2}},
+		ValidInput: true,
+	}
+	s := llm.NewService("fake")
+	s.RegisterProvider(New("fake",
+		Fixture{Match: "circle", Response: &llm.Response{Code: "script", ValidInput: true}},
+		Fixture{Match: "circle", Storyboard: board},
+		Fixture{Match: "long", Storyboard: &llm.Storyboard{Beats: []llm.Beat{{Description: "Wait", Duration: 600}}, ValidInput: true}},
+	))
+
+	got, err := s.Storyboard(context.Background(), "draw a circle", nil, "", llm.Params{})
+	if err != nil {
+		t.Fatalf("Storyboard failed: %v", err)
+	}
+	if got.Title != "Circle" || got.Model != "fake" || got.Usage.PromptTokens == 0 {
+		t.Errorf("unexpected storyboard %+v", got)
+	}
+	// The response-only fixture still answers script calls
+	if resp, err := s.Generate(context.Background(), "draw a circle", ""); err != nil || resp.Code != "script" {
+		t.Errorf("Generate got %+v, %v", resp, err)
+	}
+	if _, err := s.Storyboard(context.Background(), "a long pause", nil, "", llm.Params{}); err == nil {
+		t.Error("expected a storyboard breaking the limits to fail")
+	}
+}
@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Image is a picture attached to a prompt, e.g. a photo of a whiteboard
+// sketch to animate.
+type Image struct {
+	MediaType string `json:"media_type"` // image/png, image/jpeg, image/gif or image/webp
+	Data      []byte `json:"data"`
+}
+
+// Base64 returns the standard base64 encoding of the image data.
+func (i Image) Base64() string {
+	return base64.StdEncoding.EncodeToString(i.Data)
+}
+
+// DataURL returns the image as a data URL.
+func (i Image) DataURL() string {
+	return "data:" + i.MediaType + ";base64," + i.Base64()
+}
+
+// SupportsVision reports whether model accepts images.
+func (s *Service) SupportsVision(model string) bool {
+	info, ok := s.ModelInfo(model)
+	return ok && info.Vision
+}
+
+// requireVision fails with errors.ErrUnsupported when images are attached for
+// a model that can't see them, so fallbacks without vision are skipped.
+func (s *Service) requireVision(model string, images []Image) error {
+	if len(images) == 0 || s.SupportsVision(model) {
+		return nil
+	}
+	return fmt.Errorf("%s does not accept images: %w", model, errors.ErrUnsupported)
+}
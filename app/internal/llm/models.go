@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// minMaxTokens is the smallest completion budget a request may ask for.
+// Anything lower truncates the JSON response before the script is complete.
+const minMaxTokens = 256
+
+// ModelInfo describes a model and the generation parameters it accepts.
+type ModelInfo struct {
+	ID                 string  `json:"id"`
+	DisplayName        string  `json:"display_name"`
+	Provider           string  `json:"provider"`
+	ContextWindow      int     `json:"context_window,omitempty"`    // Tokens, 0 if unknown
+	MaxOutputTokens    int     `json:"max_output_tokens,omitempty"` // Upper bound of Params.MaxTokens, 0 if unknown
+	Streaming          bool    `json:"streaming"`
+	Storyboard         bool    `json:"storyboard"` // Supports the storyboard generation mode
+	Vision             bool    `json:"vision"`
+	Seed               bool    `json:"seed"`                    // Accepts Params.Seed
+	RelativeCost       float64 `json:"relative_cost,omitempty"` // Approximate price relative to gpt-4o-mini, 0 if unknown
+	MaxTemperature     float64 `json:"max_temperature"`         // 0 if the temperature can't be set
+	DefaultTemperature float64 `json:"default_temperature"`
+	DefaultMaxTokens   int     `json:"default_max_tokens,omitempty"`
+}
+
+// DescribedProvider is implemented by providers that expose metadata about
+// their model. Other providers are listed with their ID only.
+type DescribedProvider interface {
+	Provider
+	Info() ModelInfo
+}
+
+// Params are optional generation parameters of a single request. Unset
+// fields leave the provider defaults in place.
+type Params struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+}
+
+// IsZero reports whether no parameter is overridden.
+func (p Params) IsZero() bool {
+	return p.Temperature == nil && p.MaxTokens == 0 && p.Seed == nil
+}
+
+// Validate checks that the model described by info accepts p.
+func (p Params) Validate(info ModelInfo) error {
+	var errs []error
+	if t := p.Temperature; t != nil {
+		switch {
+		case info.MaxTemperature == 0:
+			errs = append(errs, fmt.Errorf("%s does not accept a temperature", info.ID))
+		case *t < 0 || *t > info.MaxTemperature:
+			errs = append(errs, fmt.Errorf("temperature must be between 0 and %g for %s", info.MaxTemperature, info.ID))
+		}
+	}
+	if p.MaxTokens != 0 {
+		switch {
+		case p.MaxTokens < minMaxTokens:
+			errs = append(errs, fmt.Errorf("max_tokens must be at least %d", minMaxTokens))
+		case info.MaxOutputTokens > 0 && p.MaxTokens > info.MaxOutputTokens:
+			errs = append(errs, fmt.Errorf("max_tokens must be at most %d for %s", info.MaxOutputTokens, info.ID))
+		}
+	}
+	if p.Seed != nil && !info.Seed {
+		errs = append(errs, fmt.Errorf("%s does not accept a seed", info.ID))
+	}
+	return errors.Join(errs...)
+}
+
+// For adapts p to a fallback model: parameters the model doesn't accept are
+// dropped and the token limit is capped at its maximum.
+func (p Params) For(info ModelInfo) Params {
+	if p.Temperature != nil && info.MaxTemperature == 0 {
+		p.Temperature = nil
+	} else if p.Temperature != nil && *p.Temperature > info.MaxTemperature {
+		t := info.MaxTemperature
+		p.Temperature = &t
+	}
+	if info.MaxOutputTokens > 0 && p.MaxTokens > info.MaxOutputTokens {
+		p.MaxTokens = info.MaxOutputTokens
+	}
+	if !info.Seed {
+		p.Seed = nil
+	}
+	return p
+}
+
+// describe returns the metadata of p, filling in what it doesn't report.
+func describe(p Provider) ModelInfo {
+	var info ModelInfo
+	if d, ok := p.(DescribedProvider); ok {
+		info = d.Info()
+	}
+	info.ID = p.ModelID()
+	if info.DisplayName == "" {
+		info.DisplayName = info.ID
+	}
+	_, info.Streaming = p.(StreamingProvider)
+	_, info.Storyboard = p.(StoryboardProvider)
+	return info
+}
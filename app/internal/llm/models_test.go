@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestParamsValidate(t *testing.T) {
+	info := ModelInfo{ID: "m", MaxOutputTokens: 4096, MaxTemperature: 1}
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{"defaults", Params{}, false},
+		{"in range", Params{Temperature: ptr(0.0), MaxTokens: 4096}, false},
+		{"temperature too high", Params{Temperature: ptr(1.5)}, true},
+		{"negative temperature", Params{Temperature: ptr(-0.1)}, true},
+		{"too few tokens", Params{MaxTokens: 10}, true},
+		{"too many tokens", Params{MaxTokens: 8192}, true},
+		{"seed unsupported", Params{Seed: ptr(int64(7))}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(info); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (Params{Temperature: ptr(0.5)}).Validate(ModelInfo{ID: "fixed"}); err == nil {
+		t.Error("expected error for a model without temperature support")
+	}
+}
+
+// paramsProvider records the parameters it was called with
+type paramsProvider struct {
+	stubProvider
+	info   ModelInfo
+	params Params
+}
+
+func (p *paramsProvider) Info() ModelInfo { return p.info }
+
+func (p *paramsProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	p.params = req.Params
+	return p.stubProvider.Generate(ctx, req)
+}
+
+func TestParamsAdaptedToFallback(t *testing.T) {
+	primary := &paramsProvider{
+		stubProvider: stubProvider{id: "a", err: errors.New("down")},
+		info:         ModelInfo{DisplayName: "A", MaxTemperature: 2, Seed: true},
+	}
+	fallback := &paramsProvider{
+		stubProvider: stubProvider{id: "b"},
+		info:         ModelInfo{MaxTemperature: 1, MaxOutputTokens: 1000},
+	}
+	s := NewService("a")
+	s.ConfigureRetry(RetryPolicy{MaxAttempts: 1})
+	s.RegisterProvider(primary)
+	s.RegisterProvider(fallback)
+	if err := s.SetFallbacks("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	params := Params{Temperature: ptr(1.5), MaxTokens: 2000, Seed: ptr(int64(1))}
+	if err := s.ValidateParams("", params); err != nil {
+		t.Fatalf("params should be valid for the requested model: %v", err)
+	}
+	if _, err := s.GenerateStream(context.Background(), "prompt", nil, "", params, func(Chunk) {}); err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	if primary.params.Seed == nil || *primary.params.Temperature != 1.5 {
+		t.Errorf("expected the requested model to get the params unchanged, got %+v", primary.params)
+	}
+	got := fallback.params
+	if got.Seed != nil || got.Temperature == nil || *got.Temperature != 1 || got.MaxTokens != 1000 {
+		t.Errorf("expected params capped for the fallback, got %+v", got)
+	}
+
+	if info, _ := s.ModelInfo("a"); info.ID != "a" || info.DisplayName != "A" || info.Streaming {
+		t.Errorf("unexpected model info %+v", info)
+	}
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Moderation is a moderator's verdict on a text.
+type Moderation struct {
+	Flagged    bool
+	Categories []string // Flagged categories, sorted
+}
+
+// Moderator checks user input before it is sent to a model.
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Moderation, error)
+}
+
+type moderationRule struct {
+	category string
+	re       *regexp.Regexp
+}
+
+// RuleModerator flags text matching any of a list of keywords or regular
+// expressions, without calling an external service.
+type RuleModerator struct {
+	rules []moderationRule
+}
+
+// LoadRuleModerator reads rules from a file, see NewRuleModerator.
+func LoadRuleModerator(path string) (*RuleModerator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open moderation rules: %w", err)
+	}
+	defer f.Close()
+	return NewRuleModerator(f)
+}
+
+// NewRuleModerator parses one rule per line in the form "category: pattern".
+// A pattern enclosed in slashes is a regular expression, anything else is a
+// keyword matched as a whole word. Matching ignores case. Empty lines and
+// lines starting with # are skipped.
+func NewRuleModerator(r io.Reader) (*RuleModerator, error) {
+	m := &RuleModerator{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		category, pattern, ok := strings.Cut(line, ":")
+		category, pattern = strings.TrimSpace(category), strings.TrimSpace(pattern)
+		if !ok || category == "" || pattern == "" {
+			return nil, fmt.Errorf("invalid moderation rule on line %d: expected category: pattern", n)
+		}
+
+		if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			pattern = pattern[1 : len(pattern)-1]
+		} else {
+			pattern = `\b` + regexp.QuoteMeta(pattern) + `\b`
+		}
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid moderation rule on line %d: %w", n, err)
+		}
+		m.rules = append(m.rules, moderationRule{category: category, re: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read moderation rules: %w", err)
+	}
+	return m, nil
+}
+
+func (m *RuleModerator) Moderate(_ context.Context, text string) (Moderation, error) {
+	flagged := make(map[string]bool)
+	for _, rule := range m.rules {
+		if !flagged[rule.category] && rule.re.MatchString(text) {
+			flagged[rule.category] = true
+		}
+	}
+	return NewModeration(flagged), nil
+}
+
+// NewModeration builds a verdict from a set of category flags.
+func NewModeration(categories map[string]bool) Moderation {
+	var m Moderation
+	for category, flagged := range categories {
+		if flagged {
+			m.Categories = append(m.Categories, category)
+		}
+	}
+	sort.Strings(m.Categories)
+	m.Flagged = len(m.Categories) > 0
+	return m
+}
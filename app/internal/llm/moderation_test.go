@@ -0,0 +1,44 @@
+package llm
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRuleModerator(t *testing.T) {
+	rules := `
+# keywords and regular expressions
+violence: gore
+violence: /\bbehead(ing|ed)?\b/
+self-harm: self harm
+`
+	m, err := NewRuleModerator(strings.NewReader(rules))
+	if err != nil {
+		t.Fatalf("NewRuleModerator failed: %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"draw a circle", nil},
+		{"Animate GORE and beheading", []string{"violence"}},
+		{"an animation about self harm and gore", []string{"self-harm", "violence"}},
+		{"a gorey mess", nil}, // Keywords only match whole words
+	}
+	for _, tt := range tests {
+		got, err := m.Moderate(context.Background(), tt.text)
+		if err != nil {
+			t.Fatalf("Moderate(%q) failed: %v", tt.text, err)
+		}
+		if got.Flagged != (len(tt.want) > 0) || !slices.Equal(got.Categories, tt.want) {
+			t.Errorf("Moderate(%q) = %+v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	if _, err := NewRuleModerator(strings.NewReader("no category")); err == nil {
+		t.Error("expected error for rule without category")
+	}
+}
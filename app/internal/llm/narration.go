@@ -0,0 +1,9 @@
+package llm
+
+// NarrationPrompt asks for a narration track along with the script of
+// prompt. In storyboard mode the track follows the narration of the beats.
+func NarrationPrompt(prompt string) string {
+	return prompt + "\n\nAlso write a narration track for the animation. Time each line so it is read while " +
+		"the animations it describes play, and keep lines short enough to be read in their time slot. " +
+		"If a storyboard is given, narrate each beat with its narration."
+}
@@ -0,0 +1,43 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"manimatic/internal/llm"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+type moderator struct {
+	client *openai.Client
+}
+
+// NewModerator returns a moderator backed by the OpenAI moderation endpoint.
+func NewModerator(apiKey string) llm.Moderator {
+	return &moderator{client: openai.NewClient(option.WithAPIKey(apiKey))}
+}
+
+func (m *moderator) Moderate(ctx context.Context, text string) (llm.Moderation, error) {
+	resp, err := m.client.Moderations.New(ctx, openai.ModerationNewParams{
+		Input: openai.Raw[openai.ModerationNewParamsInputUnion](text),
+	})
+	if err != nil {
+		return llm.Moderation{}, fmt.Errorf("openai moderation call failed: %w", err)
+	}
+	if len(resp.Results) == 0 {
+		return llm.Moderation{}, fmt.Errorf("no moderation results returned")
+	}
+
+	// Decode the raw categories so new categories are reported without
+	// waiting for the SDK to add them
+	result := resp.Results[0]
+	var categories map[string]bool
+	if err := json.Unmarshal([]byte(result.Categories.JSON.RawJSON()), &categories); err != nil {
+		return llm.Moderation{}, fmt.Errorf("failed to parse moderation categories: %w", err)
+	}
+	moderation := llm.NewModeration(categories)
+	moderation.Flagged = moderation.Flagged || result.Flagged
+	return moderation, nil
+}
@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultPromptID identifies the built-in DefaultSystemPrompt in the registry.
+const DefaultPromptID = "default@v2"
+
+// StoryboardPromptID identifies the built-in StoryboardSystemPrompt used to
+// plan storyboards. A template with the same ID in the prompts directory
+// replaces it.
+const StoryboardPromptID = "storyboard@v1"
+
+// promptExt is the extension of template files loaded by LoadDir. Files are
+// named <name>@<version>.tmpl, e.g. manim@v2.tmpl.
+const promptExt = ".tmpl"
+
+// PromptData is the data available to system prompt templates.
+type PromptData struct {
+	Model string // Model the prompt is rendered for
+}
+
+// PromptTemplate is a versioned system prompt.
+type PromptTemplate struct {
+	Name    string
+	Version string
+	tmpl    *template.Template
+}
+
+// ID returns the template identifier, <name>@<version>.
+func (t *PromptTemplate) ID() string {
+	return t.Name + "@" + t.Version
+}
+
+func (t *PromptTemplate) Render(data PromptData) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", t.ID(), err)
+	}
+	return b.String(), nil
+}
+
+// ParsePrompt parses a template identified by <name>@<version>.
+func ParsePrompt(id, text string) (*PromptTemplate, error) {
+	name, version, ok := strings.Cut(id, "@")
+	if !ok || name == "" || version == "" {
+		return nil, fmt.Errorf("invalid prompt id %q, expected <name>@<version>", id)
+	}
+	tmpl, err := template.New(id).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt %s: %w", id, err)
+	}
+	return &PromptTemplate{Name: name, Version: version, tmpl: tmpl}, nil
+}
+
+// PromptRegistry holds the system prompt templates and which of them each
+// model uses. It is safe for concurrent use and can be reloaded at runtime.
+type PromptRegistry struct {
+	mu        sync.RWMutex
+	builtin   map[string]*PromptTemplate
+	templates map[string]*PromptTemplate
+	models    map[string]string // Model ID -> template ID
+	defaultID string
+}
+
+func NewPromptRegistry() *PromptRegistry {
+	builtin := make(map[string]*PromptTemplate)
+	for id, text := range map[string]string{DefaultPromptID: DefaultSystemPrompt, StoryboardPromptID: StoryboardSystemPrompt} {
+		t, err := ParsePrompt(id, text)
+		if err != nil {
+			panic(err)
+		}
+		builtin[id] = t
+	}
+	return &PromptRegistry{
+		builtin:   builtin,
+		templates: builtin,
+		models:    make(map[string]string),
+		defaultID: DefaultPromptID,
+	}
+}
+
+// LoadDir (re)loads all templates in dir, replacing previously loaded ones.
+// Nothing changes if a template fails to parse or if a template in use by
+// the default or a model assignment is missing.
+func (r *PromptRegistry) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+promptExt))
+	if err != nil {
+		return fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	templates := make(map[string]*PromptTemplate, len(files)+len(r.builtin))
+	for id, t := range r.builtin {
+		templates[id] = t
+	}
+	for _, file := range files {
+		text, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt: %w", err)
+		}
+		t, err := ParsePrompt(strings.TrimSuffix(filepath.Base(file), promptExt), string(text))
+		if err != nil {
+			return err
+		}
+		templates[t.ID()] = t
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := templates[r.defaultID]; !ok {
+		return fmt.Errorf("default prompt %s is missing from %s", r.defaultID, dir)
+	}
+	for model, id := range r.models {
+		if _, ok := templates[id]; !ok {
+			return fmt.Errorf("prompt %s used by %s is missing from %s", id, model, dir)
+		}
+	}
+	r.templates = templates
+	return nil
+}
+
+// SetDefault selects the template used by models without an assignment.
+func (r *PromptRegistry) SetDefault(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[id]; !ok {
+		return fmt.Errorf("unknown prompt: %s", id)
+	}
+	r.defaultID = id
+	return nil
+}
+
+// Assign selects the template used by model.
+func (r *PromptRegistry) Assign(model, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.templates[id]; !ok {
+		return fmt.Errorf("unknown prompt: %s", id)
+	}
+	r.models[model] = id
+	return nil
+}
+
+// Storyboard returns the template used to plan storyboards.
+func (r *PromptRegistry) Storyboard() *PromptTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.templates[StoryboardPromptID]
+}
+
+// ForModel returns the template assigned to model, or the default one.
+func (r *PromptRegistry) ForModel(model string) *PromptTemplate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if id, ok := r.models[model]; ok {
+		return r.templates[id]
+	}
+	return r.templates[r.defaultID]
+}
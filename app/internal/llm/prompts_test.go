@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPromptRegistry(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("manim@v2.tmpl", "Generate Manim code with {{.Model}}.")
+
+	r := NewPromptRegistry()
+	if err := r.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	if err := r.Assign("gpt-4o", "manim@v2"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if err := r.Assign("gpt-4o", "manim@v3"); err == nil {
+		t.Error("expected error assigning unknown prompt")
+	}
+
+	if got := r.ForModel("grok-2-latest").ID(); got != DefaultPromptID {
+		t.Errorf("ForModel(grok-2-latest) = %s, want %s", got, DefaultPromptID)
+	}
+	got, err := r.ForModel("gpt-4o").Render(PromptData{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if want := "Generate Manim code with gpt-4o."; got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+
+	// A reload that drops a template in use is rejected
+	os.Remove(filepath.Join(dir, "manim@v2.tmpl"))
+	write("manim@v3.tmpl", "{{.Model}}")
+	if err := r.LoadDir(dir); err == nil {
+		t.Error("expected error reloading without an assigned prompt")
+	}
+	if got := r.ForModel("gpt-4o").ID(); got != "manim@v2" {
+		t.Errorf("ForModel(gpt-4o) = %s after failed reload, want manim@v2", got)
+	}
+
+	// Invalid templates are rejected too
+	write("manim@v2.tmpl", "{{.Model")
+	if err := r.LoadDir(dir); err == nil {
+		t.Error("expected error loading invalid template")
+	}
+}
@@ -0,0 +1,182 @@
+// Package providers builds the llm.Service described by the configuration,
+// shared by the API and the offline tools.
+package providers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"manimatic/internal/config"
+	"manimatic/internal/llm"
+	"manimatic/internal/llm/anthropic"
+	"manimatic/internal/llm/cassette"
+	"manimatic/internal/llm/compat"
+	"manimatic/internal/llm/fake"
+	"manimatic/internal/llm/openai"
+	"manimatic/internal/llm/xai"
+	"manimatic/internal/worker/manimexec/security"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// NewService registers the providers of the configured LLM mode and applies
+// the breaker, retry, fallback, few-shot and prompt settings. With discovery
+// enabled it also returns the discoverers to refresh the models with; they
+// are not listed yet, Discover does that without holding up the caller. The
+// response cache is left to the caller.
+func NewService(cfg *config.Config, logger *slog.Logger) (*llm.Service, []*llm.Discoverer, error) {
+	defaultModel := openai.ChatModelGPT4o
+	var fixtures *fake.Fixtures
+	if cfg.LLM.Mode == "fake" {
+		var err error
+		fixtures, err = fake.Load(cfg.LLM.FakeFixtures)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load fake provider fixtures: %w", err)
+		}
+		defaultModel = fixtures.Models[0]
+	}
+	llmService := llm.NewService(defaultModel)
+	llmService.ConfigureBreakers(cfg.LLM.BreakerThreshold, cfg.LLM.BreakerCooldown)
+	llmService.ConfigureRetry(llm.RetryPolicy{
+		MaxAttempts: cfg.LLM.RetryAttempts,
+		BaseDelay:   cfg.LLM.RetryBaseDelay,
+		MaxDelay:    cfg.LLM.RetryMaxDelay,
+		Timeout:     cfg.LLM.AttemptTimeout,
+	})
+	var discoverers []*llm.Discoverer
+	if fixtures != nil {
+		logger.Info("using fake LLM provider", "fixtures", cfg.LLM.FakeFixtures, "models", fixtures.Models)
+		fake.RegisterWith(llmService, fixtures)
+	} else {
+		discoverers = register(cfg, logger, llmService)
+	}
+	if len(discoverers) == 0 {
+		configureFallbacks(cfg, logger, llmService)
+	}
+	examples, err := llm.DefaultExamples()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load examples: %w", err)
+	}
+	llmService.ConfigureExamples(examples, cfg.LLM.FewShotExamples)
+	llmService.SetValidator(security.NewValidator(nil))
+
+	prompts := llmService.Prompts()
+	if cfg.LLM.PromptsDir != "" {
+		if err := prompts.LoadDir(cfg.LLM.PromptsDir); err != nil {
+			return nil, nil, fmt.Errorf("failed to load prompts: %w", err)
+		}
+	}
+	if cfg.LLM.PromptDefault != "" {
+		if err := prompts.SetDefault(cfg.LLM.PromptDefault); err != nil {
+			return nil, nil, fmt.Errorf("failed to select default prompt: %w", err)
+		}
+	}
+	for model, id := range cfg.LLM.PromptModels {
+		if err := prompts.Assign(model, id); err != nil {
+			logger.Error("failed to assign prompt", "model", model, "prompt", id, "error", err)
+		}
+	}
+	return llmService, discoverers, nil
+}
+
+// Discover lists the models of the discoverers for the first time, then
+// configures the fallback chains, which may name discovered models.
+func Discover(ctx context.Context, cfg *config.Config, logger *slog.Logger, llmService *llm.Service, discoverers []*llm.Discoverer) {
+	SyncModels(ctx, logger, llmService, discoverers)
+	configureFallbacks(cfg, logger, llmService)
+}
+
+func configureFallbacks(cfg *config.Config, logger *slog.Logger, llmService *llm.Service) {
+	for model, fallbacks := range cfg.LLM.Fallbacks {
+		if err := llmService.SetFallbacks(model, fallbacks...); err != nil {
+			logger.Error("failed to configure fallback chain", "model", model, "error", err)
+		}
+	}
+}
+
+// SyncModels registers the models the discoverers list and unregisters the
+// ones they no longer do. A provider that can't be listed keeps its models.
+func SyncModels(ctx context.Context, logger *slog.Logger, llmService *llm.Service, discoverers []*llm.Discoverer) {
+	for _, d := range discoverers {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		added, removed, err := d.Sync(ctx, llmService)
+		cancel()
+		if err != nil {
+			logger.Error("failed to discover models", "provider", d.Name, "error", err)
+			continue
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			logger.Info("discovered models", "provider", d.Name, "added", added, "removed", removed)
+		}
+	}
+}
+
+// register registers the real providers. In record and replay mode their
+// HTTP traffic goes through a cassette per provider in CassetteDir. With
+// discovery enabled it returns the discoverers of the OpenAI-compatible
+// providers, which list their models at /v1/models.
+func register(cfg *config.Config, logger *slog.Logger, llmService *llm.Service) []*llm.Discoverer {
+	var discoverers []*llm.Discoverer
+	cassetteClient := func(name string) (*http.Client, bool) {
+		if cfg.LLM.Mode != "record" && cfg.LLM.Mode != "replay" {
+			return nil, true
+		}
+		path := filepath.Join(cfg.LLM.CassetteDir, name+".json")
+		t, err := cassette.New(path, cassette.Mode(cfg.LLM.Mode), nil)
+		if err != nil {
+			logger.Warn("provider disabled, cassette unavailable", "provider", name, "error", err)
+			return nil, false
+		}
+		logger.Info("using cassette", "provider", name, "mode", cfg.LLM.Mode, "path", path)
+		return t.Client(), true
+	}
+
+	type endpoint struct {
+		opts     compat.Options
+		patterns config.ModelPatterns
+	}
+	endpoints := []endpoint{
+		{openai.Options(cfg.OpenAI.Key), cfg.LLM.OpenAIModels},
+		{xai.Options(cfg.XAI.Key), cfg.LLM.XAIModels},
+	}
+	for _, p := range cfg.Compat {
+		endpoints = append(endpoints, endpoint{compat.Options{
+			Name:       p.Name,
+			BaseURL:    p.BaseURL,
+			APIKey:     p.APIKey.Key,
+			PathPrefix: p.PathPrefix,
+			Models:     p.Models,
+		}, p.Discovered})
+	}
+	for _, e := range endpoints {
+		client, ok := cassetteClient(e.opts.Name)
+		if !ok {
+			continue
+		}
+		e.opts.HTTPClient = client
+		if err := compat.RegisterWith(llmService, e.opts); err != nil {
+			logger.Error("failed to register provider", "provider", e.opts.Name, "error", err)
+			continue
+		}
+		if cfg.LLM.Discovery {
+			d, err := compat.NewDiscoverer(e.opts)
+			if err != nil {
+				logger.Error("failed to create model discoverer", "provider", e.opts.Name, "error", err)
+				continue
+			}
+			d.Filter = llm.ModelFilter{Allow: e.patterns.Allow, Deny: e.patterns.Deny}
+			discoverers = append(discoverers, d)
+		}
+	}
+	if client, ok := cassetteClient("anthropic"); ok {
+		var opts []anthropic.Option
+		if client != nil {
+			opts = append(opts, anthropic.WithHTTPClient(client))
+		}
+		if err := anthropic.RegisterWith(llmService, cfg.Anthropic.Key, opts...); err != nil {
+			logger.Error("failed to register provider", "provider", "anthropic", "error", err)
+		}
+	}
+	return discoverers
+}
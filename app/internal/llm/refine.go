@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is a single turn of a conversation with the model.
+type Message struct {
+	Role    Role    `json:"role"`
+	Content string  `json:"content"`
+	Images  []Image `json:"images,omitempty"` // Only sent in user turns
+}
+
+// RefineRequest asks the model to edit an existing script.
+type RefineRequest struct {
+	System      string    // Rendered system prompt, DefaultSystemPrompt if empty
+	Examples    []Message // Few-shot turns sent before the conversation
+	History     []Message // Prior turns, oldest first
+	Script      string    // The script to edit
+	Instruction string    // What the user wants changed
+	Params      Params    // Overridden generation parameters
+}
+
+// ConversationalProvider is implemented by providers that accept a
+// multi-turn conversation. Providers that don't are refined through
+// Generate with the conversation flattened into a single prompt.
+type ConversationalProvider interface {
+	Provider
+	Refine(ctx context.Context, req RefineRequest) (Response, error)
+}
+
+// RefineMessages returns the conversation to send to the model: the few-shot
+// examples and prior turns followed by a user turn carrying the current
+// script and instruction.
+func RefineMessages(req RefineRequest) []Message {
+	msgs := make([]Message, 0, len(req.Examples)+len(req.History)+1)
+	msgs = append(msgs, req.Examples...)
+	msgs = append(msgs, req.History...)
+	msgs = append(msgs, Message{Role: RoleUser, Content: refineInstruction(req)})
+	return msgs
+}
+
+func refineInstruction(req RefineRequest) string {
+	var b strings.Builder
+	b.WriteString("Here is the current Manim script:\n")
+	b.WriteString(req.Script)
+	b.WriteString("\n\nEdit this script according to the following request. ")
+	b.WriteString("Keep everything that is not affected by the request unchanged and return the full script.\n\n")
+	b.WriteString("Request:\n")
+	b.WriteString(req.Instruction)
+	return b.String()
+}
+
+// SystemPrompt returns the system prompt to send with the request.
+func (r RefineRequest) SystemPrompt() string {
+	return Request{System: r.System}.SystemPrompt()
+}
+
+// flattenRefine turns a refine request into a single request for providers
+// without multi-turn support.
+func flattenRefine(req RefineRequest) Request {
+	var b strings.Builder
+	if len(req.History) > 0 {
+		b.WriteString("Conversation so far:\n")
+		for _, m := range req.History {
+			b.WriteString(fmt.Sprintf("%s: %s\n", m.Role, m.Content))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(refineInstruction(req))
+	return Request{System: req.System, Examples: req.Examples, Prompt: b.String(), Params: req.Params}
+}
+
+// Refine edits an existing script following a user instruction, taking the
+// prior conversation into account.
+func (s *Service) Refine(ctx context.Context, req RefineRequest, model string) (Response, error) {
+	if req.Examples == nil {
+		req.Examples = s.fewShot(req.Instruction)
+	}
+	return s.call(ctx, model, req.Params, func(p Provider, system string, params Params) (Response, error) {
+		req.System = system
+		req.Params = params
+		conv, ok := p.(ConversationalProvider)
+		if !ok {
+			return p.Generate(ctx, flattenRefine(req))
+		}
+		return conv.Refine(ctx, req)
+	})
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxRepairErrorSize bounds how much compiler output is sent back to the
+// model. Python tracebacks put the useful part at the end, so the tail is kept.
+const maxRepairErrorSize = 4_000
+
+// RepairRequest describes a generated script that failed to compile or was
+// rejected by validation before compiling.
+type RepairRequest struct {
+	Prompt    string // The original user prompt
+	Script    string // The script that failed
+	Stderr    string // Compiler output
+	Violation string // Validation error, set instead of Stderr when the script was rejected
+	Line      int    // Line the error was reported on, 0 if unknown
+}
+
+// RepairPrompt builds a prompt asking the model to fix a failing script while
+// keeping the original intent.
+func RepairPrompt(req RepairRequest) string {
+	stderr := req.Stderr
+	if len(stderr) > maxRepairErrorSize {
+		stderr = "..." + stderr[len(stderr)-maxRepairErrorSize:]
+	}
+
+	var b strings.Builder
+	if req.Violation != "" {
+		b.WriteString("The following Manim script was generated for the request below but was rejected by the sandbox before compiling.\n")
+		b.WriteString("Scripts may only import manim, numpy and the standard math helpers, and must not access files, the network, ")
+		b.WriteString("processes or interpreter internals.\n")
+	} else {
+		b.WriteString("The following Manim script was generated for the request below but failed to compile.\n")
+	}
+	b.WriteString("Return a corrected version of the full script that still fulfils the original request.\n\n")
+	b.WriteString("Original request:\n")
+	b.WriteString(req.Prompt)
+	b.WriteString("\n\nFailing script:\n")
+	b.WriteString(req.Script)
+	if req.Line > 0 {
+		b.WriteString(fmt.Sprintf("\n\nThe error was reported at line %d.", req.Line))
+	}
+	if req.Violation != "" {
+		b.WriteString("\n\nValidation error:\n")
+		b.WriteString(req.Violation)
+		return b.String()
+	}
+	b.WriteString("\n\nCompiler output:\n")
+	b.WriteString(stderr)
+	return b.String()
+}
+
+// Repair asks the model to fix a script that failed to compile or validate.
+func (s *Service) Repair(ctx context.Context, req RepairRequest, model string) (Response, error) {
+	// Examples are chosen from the original prompt, not the compiler output
+	examples := s.fewShot(req.Prompt)
+	prompt := RepairPrompt(req)
+	return s.call(ctx, model, Params{}, func(p Provider, system string, params Params) (Response, error) {
+		return p.Generate(ctx, Request{System: system, Examples: examples, Prompt: prompt})
+	})
+}
@@ -0,0 +1,235 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// RetryPolicy controls how failed provider calls are retried.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first, < 2 disables retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled for each further retry
+	MaxDelay    time.Duration // Upper bound for a single delay, including Retry-After
+	Timeout     time.Duration // Deadline for each attempt, 0 for none
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+	Timeout:     90 * time.Second,
+}
+
+// HTTPError is implemented by provider errors that carry the HTTP status of
+// the failed call and, optionally, the delay requested by the server.
+type HTTPError interface {
+	error
+	HTTPStatus() int
+	RetryAfter() time.Duration
+}
+
+// retryProvider decorates a provider with retries. It implements all optional
+// provider interfaces and falls back the same way Service does when the
+// wrapped provider lacks one. Storyboards have no fallback and fail with
+// errors.ErrUnsupported.
+type retryProvider struct {
+	Provider
+	policy RetryPolicy
+}
+
+// WithRetry wraps p so that retryable failures are retried according to policy.
+func WithRetry(p Provider, policy RetryPolicy) Provider {
+	return &retryProvider{Provider: p, policy: policy}
+}
+
+func (r *retryProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	return r.policy.do(ctx, func(ctx context.Context) (Response, error) {
+		return r.Provider.Generate(ctx, req)
+	})
+}
+
+func (r *retryProvider) GenerateStream(ctx context.Context, req Request, onChunk func(Chunk)) (Response, error) {
+	streamer, ok := r.Provider.(StreamingProvider)
+	if !ok {
+		return r.Generate(ctx, req)
+	}
+	return r.policy.do(ctx, func(ctx context.Context) (Response, error) {
+		return streamer.GenerateStream(ctx, req, onChunk)
+	})
+}
+
+func (r *retryProvider) Refine(ctx context.Context, req RefineRequest) (Response, error) {
+	conv, ok := r.Provider.(ConversationalProvider)
+	if !ok {
+		return r.Generate(ctx, flattenRefine(req))
+	}
+	return r.policy.do(ctx, func(ctx context.Context) (Response, error) {
+		return conv.Refine(ctx, req)
+	})
+}
+
+func (r *retryProvider) GenerateStoryboard(ctx context.Context, req Request) (Storyboard, error) {
+	sp, ok := r.Provider.(StoryboardProvider)
+	if !ok {
+		return Storyboard{}, fmt.Errorf("%s does not support storyboards: %w", r.ModelID(), errors.ErrUnsupported)
+	}
+	var board Storyboard
+	_, err := r.policy.do(ctx, func(ctx context.Context) (Response, error) {
+		var err error
+		board, err = sp.GenerateStoryboard(ctx, req)
+		return Response{}, err
+	})
+	return board, err
+}
+
+func (p RetryPolicy) do(ctx context.Context, call func(context.Context) (Response, error)) (Response, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		var resp Response
+		resp, err = p.attempt(ctx, call)
+		if err == nil {
+			return resp, nil
+		}
+
+		if attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return Response{}, err
+		}
+
+		retryable, retryAfter := classify(err)
+		if !retryable {
+			return Response{}, err
+		}
+
+		delay := p.backoff(attempt)
+		if retryAfter > 0 {
+			if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+				// The server wants us to wait longer than we are willing to
+				return Response{}, err
+			}
+			delay = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return Response{}, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (p RetryPolicy) attempt(ctx context.Context, call func(context.Context) (Response, error)) (Response, error) {
+	if p.Timeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	return call(ctx)
+}
+
+// backoff returns an exponential delay for the given attempt with jitter in
+// the upper half of the interval.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// classify reports whether err is worth retrying and how long the server
+// asked us to wait, if at all. Only the caller's own cancellation is handled
+// by do; a per-attempt deadline surfaces here as DeadlineExceeded and is retried.
+func classify(err error) (bool, time.Duration) {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		var retryAfter time.Duration
+		if apiErr.Response != nil {
+			retryAfter = ParseRetryAfter(apiErr.Response.Header)
+		}
+		return retryableStatus(apiErr.StatusCode), retryAfter
+	}
+
+	var httpErr HTTPError
+	if errors.As(err, &httpErr) {
+		return retryableStatus(httpErr.HTTPStatus()), httpErr.RetryAfter()
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true, 0
+	}
+
+	return false, 0
+}
+
+// providerFailure reports whether err says something about the health of the
+// provider: a retryable error, or a rejected API key. Anything else, like a
+// bad request or an unparsable reply, is a problem with that one call.
+func providerFailure(err error) bool {
+	if retry, _ := classify(err); retry {
+		return true
+	}
+	status := 0
+	var apiErr *openai.Error
+	var httpErr HTTPError
+	switch {
+	case errors.As(err, &apiErr):
+		status = apiErr.StatusCode
+	case errors.As(err, &httpErr):
+		status = httpErr.HTTPStatus()
+	}
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+func retryableStatus(status int) bool {
+	switch {
+	case status == http.StatusRequestTimeout,
+		status == http.StatusConflict,
+		status == http.StatusTooManyRequests:
+		return true
+	case status >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseRetryAfter reads Retry-After-Ms or Retry-After, the latter either in
+// seconds or as an HTTP date.
+func ParseRetryAfter(h http.Header) time.Duration {
+	if v := h.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type statusError struct {
+	status     int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string             { return http.StatusText(e.status) }
+func (e *statusError) HTTPStatus() int           { return e.status }
+func (e *statusError) RetryAfter() time.Duration { return e.retryAfter }
+
+// scriptedProvider fails with the given errors, in order, before succeeding
+type scriptedProvider struct {
+	errs  []error
+	calls int
+	delay time.Duration
+}
+
+func (p *scriptedProvider) ModelID() string { return "scripted" }
+
+func (p *scriptedProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	p.calls++
+	if p.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx.Err()
+		case <-time.After(p.delay):
+		}
+	}
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return Response{}, err
+	}
+	return Response{Code: "ok", ValidInput: true}, nil
+}
+
+var fastPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "rate limited then success", errs: []error{&statusError{status: 429}}, wantCalls: 2},
+		{name: "server errors exhaust attempts", errs: []error{&statusError{status: 500}, &statusError{status: 502}, &statusError{status: 503}}, wantCalls: 3, wantErr: true},
+		{name: "client error is terminal", errs: []error{&statusError{status: 400}}, wantCalls: 1, wantErr: true},
+		{name: "unknown error is terminal", errs: []error{errors.New("failed to parse response")}, wantCalls: 1, wantErr: true},
+		{name: "retry-after above max delay is terminal", errs: []error{&statusError{status: 429, retryAfter: time.Minute}}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &scriptedProvider{errs: tt.errs}
+			_, err := WithRetry(p, fastPolicy).Generate(context.Background(), Request{Prompt: "prompt"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if p.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, p.calls)
+			}
+		})
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	p := &scriptedProvider{errs: []error{&statusError{status: 503, retryAfter: 30 * time.Millisecond}}}
+
+	start := time.Now()
+	if _, err := WithRetry(p, fastPolicy).Generate(context.Background(), Request{Prompt: "prompt"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected to wait for Retry-After, returned after %s", elapsed)
+	}
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	p := &scriptedProvider{delay: 50 * time.Millisecond}
+	policy := fastPolicy
+	policy.Timeout = 10 * time.Millisecond
+
+	_, err := WithRetry(p, policy).Generate(context.Background(), Request{Prompt: "prompt"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if p.calls != policy.MaxAttempts {
+		t.Errorf("expected timed out attempts to be retried, got %d calls", p.calls)
+	}
+}
+
+func TestRetryStopsWhenCallerCancels(t *testing.T) {
+	p := &scriptedProvider{errs: []error{&statusError{status: 500}, &statusError{status: 500}}}
+	policy := fastPolicy
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := WithRetry(p, policy).Generate(ctx, Request{Prompt: "prompt"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if p.calls != 1 {
+		t.Errorf("expected no retry after cancellation, got %d calls", p.calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	h := http.Header{}
+	h.Set("Retry-After", "2")
+	if got := ParseRetryAfter(h); got != 2*time.Second {
+		t.Errorf("expected 2s, got %s", got)
+	}
+	h.Set("Retry-After-Ms", "150")
+	if got := ParseRetryAfter(h); got != 150*time.Millisecond {
+		t.Errorf("expected 150ms, got %s", got)
+	}
+}
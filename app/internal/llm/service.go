@@ -2,27 +2,88 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
+	"sync"
+	"time"
 )
 
 type Service struct {
+	// mu guards the registered models, which change at runtime when models
+	// are discovered from the provider APIs.
+	mu           sync.RWMutex
 	providers    map[string]Provider
+	models       map[string]ModelInfo
 	defaultModel string
 	modelCache   []string
+
+	fallbacks        map[string][]string
+	breakers         map[string]*breaker
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	retryPolicy      RetryPolicy
+	prompts          *PromptRegistry
+	examples         *ExampleLibrary
+	numExamples      int
+	cache            Cache
+	validator        ScriptValidator
 }
 
 func NewService(defaultModel string) *Service {
 	return &Service{
-		providers:    make(map[string]Provider),
-		defaultModel: defaultModel,
+		providers:        make(map[string]Provider),
+		models:           make(map[string]ModelInfo),
+		defaultModel:     defaultModel,
+		fallbacks:        make(map[string][]string),
+		breakers:         make(map[string]*breaker),
+		breakerThreshold: DefaultBreakerThreshold,
+		breakerCooldown:  DefaultBreakerCooldown,
+		retryPolicy:      DefaultRetryPolicy,
+		prompts:          NewPromptRegistry(),
 	}
 }
 
+// ModelError reports which model a failed call was last attempted with.
+type ModelError struct {
+	Model string
+	Err   error
+}
+
+func (e *ModelError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Model, e.Err)
+}
+
+func (e *ModelError) Unwrap() error {
+	return e.Err
+}
+
+// RegisterProvider adds the model of provider, replacing a provider
+// registered for the same model. It is safe to call while serving requests.
 func (s *Service) RegisterProvider(provider Provider) {
-	s.providers[provider.ModelID()] = provider
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.providers[provider.ModelID()] = WithRetry(provider, s.retryPolicy)
+	s.models[provider.ModelID()] = describe(provider)
+	s.breakers[provider.ModelID()] = newBreaker(s.breakerThreshold, s.breakerCooldown)
+	s.updateModelCache()
+}
+
+// UnregisterProvider removes a model. Fallback chains that name it skip it
+// until it is registered again.
+func (s *Service) UnregisterProvider(modelID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.providers, modelID)
+	delete(s.models, modelID)
+	delete(s.breakers, modelID)
 	s.updateModelCache()
 }
+
+// updateModelCache must be called with mu held.
 func (s *Service) updateModelCache() {
 	s.modelCache = make([]string, 0, len(s.providers))
 	for modelID := range s.providers {
@@ -32,22 +93,290 @@ func (s *Service) updateModelCache() {
 	sort.Strings(s.modelCache)
 }
 
-func (s *Service) Generate(ctx context.Context, prompt string, model string) (Response, error) {
+// ConfigureBreakers sets the circuit breaker policy; a threshold of 0 disables
+// the breakers. It must be called before providers are registered.
+func (s *Service) ConfigureBreakers(threshold int, cooldown time.Duration) {
+	s.breakerThreshold = threshold
+	s.breakerCooldown = cooldown
+}
+
+// ConfigureRetry sets the retry policy applied to every provider. It must be
+// called before providers are registered.
+func (s *Service) ConfigureRetry(policy RetryPolicy) {
+	s.retryPolicy = policy
+}
+
+// SetFallbacks sets the models tried, in order, when model fails or its
+// circuit breaker is open.
+func (s *Service) SetFallbacks(model string, fallbacks ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.providers[model]; !exists {
+		return fmt.Errorf("unsupported model: %s", model)
+	}
+	for _, fb := range fallbacks {
+		if _, exists := s.providers[fb]; !exists {
+			return fmt.Errorf("unsupported fallback model for %s: %s", model, fb)
+		}
+	}
+	s.fallbacks[model] = fallbacks
+	return nil
+}
+
+// ConfigureExamples sets the library few-shot examples are selected from and
+// how many of them are sent with each request. n <= 0 disables them.
+func (s *Service) ConfigureExamples(lib *ExampleLibrary, n int) {
+	s.examples = lib
+	s.numExamples = n
+}
+
+// fewShot returns the example turns to send before a request about text
+func (s *Service) fewShot(text string) []Message {
+	return ExampleMessages(s.examples.Select(text, s.numExamples))
+}
+
+// SetCache puts c in front of Generate and GenerateStream, which serve the
+// responses passed to Remember. A nil cache disables caching.
+func (s *Service) SetCache(c Cache) {
+	s.cache = c
+}
+
+// cached looks up a previous response to prompt from model, returning the
+// key to store the new response under on a miss. Requests that override
+// generation parameters or attach images bypass the cache.
+func (s *Service) cached(ctx context.Context, prompt string, images []Image, model string, params Params) (string, Response, bool) {
+	if s.cache == nil || !params.IsZero() || len(images) > 0 {
+		return "", Response{}, false
+	}
+	if model == "" {
+		model = s.defaultModel
+	}
+	key := CacheKey(prompt, model, s.prompts.ForModel(model).ID())
+	resp, ok := s.cache.Get(ctx, key)
+	if !ok {
+		return key, Response{}, false
+	}
+	resp.Cached = true
+	resp.Usage = Usage{}
+	return key, resp, true
+}
+
+// cacheable marks a usable response produced by the requested model to be
+// cached under key by Remember; answers from fallbacks are not cached so the
+// model recovers once it is back.
+func (s *Service) cacheable(key, model string, resp Response) Response {
+	if key == "" || !resp.ValidInput || resp.Code == "" {
+		return resp
+	}
+	if model == "" {
+		model = s.defaultModel
+	}
+	if resp.Model == model {
+		resp.cacheKey = key
+	}
+	return resp
+}
+
+// Remember caches a response of Generate, GenerateStream or
+// GenerateCandidates. Callers remember a response once its script passed
+// their checks, so a rejected script is generated again rather than replayed.
+func (s *Service) Remember(ctx context.Context, resp Response) {
+	if s.cache == nil || resp.cacheKey == "" || resp.Cached {
+		return
+	}
+	s.cache.Set(ctx, resp.cacheKey, resp)
+}
+
+// Prompts returns the registry of system prompts used by the service.
+func (s *Service) Prompts() *PromptRegistry {
+	return s.prompts
+}
+
+// call runs fn against the provider of model and, if it fails, against the
+// configured fallbacks. Models with an open circuit breaker are skipped. fn
+// receives the system prompt rendered for the model being tried and params
+// adapted to what that model accepts.
+func (s *Service) call(ctx context.Context, model string, params Params, fn func(p Provider, system string, params Params) (Response, error)) (Response, error) {
+	return s.callWith(ctx, model, params, s.prompts.ForModel, fn)
+}
+
+// callWith is call with the system prompt of each model chosen by prompt.
+func (s *Service) callWith(ctx context.Context, model string, params Params, prompt func(model string) *PromptTemplate, fn func(p Provider, system string, params Params) (Response, error)) (Response, error) {
 	if model == "" {
 		model = s.defaultModel
 	}
 
-	provider, exists := s.providers[model]
+	s.mu.RLock()
+	_, exists := s.providers[model]
+	chain := append([]string{model}, s.fallbacks[model]...)
+	s.mu.RUnlock()
 	if !exists {
 		return Response{}, fmt.Errorf("unsupported model: %s", model)
 	}
 
-	return provider.Generate(ctx, prompt)
+	var lastErr error
+	for _, m := range chain {
+		s.mu.RLock()
+		p, registered := s.providers[m]
+		b, info := s.breakers[m], s.models[m]
+		s.mu.RUnlock()
+		if !registered {
+			// Unregistered since the chain was configured
+			lastErr = &ModelError{Model: m, Err: fmt.Errorf("unsupported model: %s", m)}
+			continue
+		}
+		if !b.allow() {
+			lastErr = &ModelError{Model: m, Err: ErrCircuitOpen}
+			continue
+		}
+
+		tmpl := prompt(m)
+		system, err := tmpl.Render(PromptData{Model: m})
+		if err != nil {
+			b.release()
+			lastErr = &ModelError{Model: m, Err: err}
+			continue
+		}
+
+		resp, err := fn(p, system, params.For(info))
+		if err != nil {
+			if errors.Is(err, errors.ErrUnsupported) {
+				// The model can't do this, it isn't failing
+				b.release()
+				lastErr = &ModelError{Model: m, Err: err}
+				continue
+			}
+			if ctx.Err() != nil {
+				// The caller gave up; that says nothing about the provider
+				b.release()
+				return Response{}, &ModelError{Model: m, Err: err}
+			}
+			if providerFailure(err) {
+				b.failure()
+			} else {
+				// A failed request, not a failing provider
+				b.release()
+			}
+			lastErr = &ModelError{Model: m, Err: err}
+			continue
+		}
+
+		b.success()
+		resp.Model = m
+		resp.Prompt = tmpl.ID()
+		return resp, nil
+	}
+
+	return Response{}, lastErr
+}
+
+func (s *Service) Generate(ctx context.Context, prompt string, model string) (Response, error) {
+	key, resp, ok := s.cached(ctx, prompt, nil, model, Params{})
+	if ok {
+		return resp, nil
+	}
+
+	examples := s.fewShot(prompt)
+	resp, err := s.call(ctx, model, Params{}, func(p Provider, system string, params Params) (Response, error) {
+		return p.Generate(ctx, Request{System: system, Examples: examples, Prompt: prompt})
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return s.cacheable(key, model, resp), nil
+}
+
+// GenerateStream behaves like Generate but attaches images, applies params
+// and reports partial output through onChunk when the selected provider
+// supports streaming. Providers that do not stream fall back to Generate and
+// never invoke onChunk.
+func (s *Service) GenerateStream(ctx context.Context, prompt string, images []Image, model string, params Params, onChunk func(Chunk)) (Response, error) {
+	key, resp, ok := s.cached(ctx, prompt, images, model, params)
+	if ok {
+		return resp, nil
+	}
+
+	examples := s.fewShot(prompt)
+	resp, err := s.call(ctx, model, params, func(p Provider, system string, params Params) (Response, error) {
+		if err := s.requireVision(p.ModelID(), images); err != nil {
+			return Response{}, err
+		}
+		req := Request{System: system, Examples: examples, Prompt: prompt, Images: images, Params: params}
+		streamer, ok := p.(StreamingProvider)
+		if !ok {
+			return p.Generate(ctx, req)
+		}
+		return streamer.GenerateStream(ctx, req, onChunk)
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return s.cacheable(key, model, resp), nil
 }
 
 func (s *Service) AvailableModels() []string {
-	return s.modelCache
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.modelCache)
+}
+
+// Models returns the metadata of every registered model, sorted by ID.
+func (s *Service) Models() []ModelInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	models := make([]ModelInfo, 0, len(s.modelCache))
+	for _, modelID := range s.modelCache {
+		models = append(models, s.models[modelID])
+	}
+	return models
 }
+
+// ModelInfo returns the metadata of model, the default model if empty.
+func (s *Service) ModelInfo(model string) (ModelInfo, bool) {
+	if model == "" {
+		model = s.defaultModel
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	info, ok := s.models[model]
+	return info, ok
+}
+
+// ValidateParams checks params against the capabilities of model.
+func (s *Service) ValidateParams(model string, params Params) error {
+	info, ok := s.ModelInfo(model)
+	if !ok {
+		return fmt.Errorf("unsupported model: %s", model)
+	}
+	return params.Validate(info)
+}
+
+// UnavailableModels returns the models whose circuit breaker is currently open.
+func (s *Service) UnavailableModels() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	unavailable := []string{}
+	for _, modelID := range s.modelCache {
+		if !s.breakers[modelID].available() {
+			unavailable = append(unavailable, modelID)
+		}
+	}
+	return unavailable
+}
+
 func (s *Service) DefaultModel() string {
 	return s.defaultModel
 }
+
+// FailedModel returns the model a failed call was last attempted with, or
+// fallback if err doesn't carry one.
+func FailedModel(err error, fallback string) string {
+	var modelErr *ModelError
+	if errors.As(err, &modelErr) {
+		return modelErr.Model
+	}
+	return fallback
+}
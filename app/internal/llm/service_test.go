@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+type stubProvider struct {
+	id    string
+	err   error
+	calls int
+}
+
+func (p *stubProvider) ModelID() string { return p.id }
+
+func (p *stubProvider) Generate(ctx context.Context, req Request) (Response, error) {
+	p.calls++
+	if p.err != nil {
+		return Response{}, p.err
+	}
+	return Response{Code: p.id, ValidInput: true}, nil
+}
+
+func TestGenerateFallback(t *testing.T) {
+	primary := &stubProvider{id: "a", err: errors.New("rate limited")}
+	secondary := &stubProvider{id: "b", err: errors.New("overloaded")}
+	last := &stubProvider{id: "c"}
+
+	s := NewService("a")
+	s.RegisterProvider(primary)
+	s.RegisterProvider(secondary)
+	s.RegisterProvider(last)
+	if err := s.SetFallbacks("a", "b", "c"); err != nil {
+		t.Fatalf("SetFallbacks failed: %v", err)
+	}
+
+	resp, err := s.Generate(context.Background(), "prompt", "")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Model != "c" || resp.Code != "c" {
+		t.Errorf("expected response from c, got %+v", resp)
+	}
+
+	last.err = errors.New("down")
+	_, err = s.Generate(context.Background(), "prompt", "a")
+	if got := FailedModel(err, ""); got != "c" {
+		t.Errorf("expected failed model c, got %q", got)
+	}
+
+	if err := s.SetFallbacks("a", "missing"); err == nil {
+		t.Error("expected error for unknown fallback model")
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	p := &stubProvider{id: "a"}
+	s := NewService("a")
+	s.ConfigureBreakers(2, 20*time.Millisecond)
+	s.ConfigureRetry(RetryPolicy{MaxAttempts: 1})
+	s.RegisterProvider(p)
+
+	// Failed requests say nothing about the provider's health
+	for _, err := range []error{&statusError{status: http.StatusBadRequest}, errors.New("failed to parse response")} {
+		p.err = err
+		for range 2 {
+			_, _ = s.Generate(context.Background(), "prompt", "a")
+		}
+		if len(s.UnavailableModels()) != 0 {
+			t.Fatalf("expected %q not to open the breaker", err)
+		}
+	}
+
+	p.err, p.calls = &statusError{status: http.StatusServiceUnavailable}, 0
+	for range 2 {
+		_, _ = s.Generate(context.Background(), "prompt", "a")
+	}
+	if !slices.Contains(s.UnavailableModels(), "a") {
+		t.Fatalf("expected a to be unavailable after repeated failures")
+	}
+
+	_, err := s.Generate(context.Background(), "prompt", "a")
+	if !errors.Is(err, ErrCircuitOpen) || p.calls != 2 {
+		t.Errorf("expected open circuit to short-circuit the call, got %v after %d calls", err, p.calls)
+	}
+
+	// After the cooldown a single trial call closes the breaker again
+	time.Sleep(25 * time.Millisecond)
+	p.err = nil
+	if _, err := s.Generate(context.Background(), "prompt", "a"); err != nil {
+		t.Fatalf("expected trial call to succeed, got %v", err)
+	}
+	if len(s.UnavailableModels()) != 0 {
+		t.Errorf("expected breaker to close after a successful call")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	p := &stubProvider{id: "a", err: &statusError{status: http.StatusServiceUnavailable}}
+	s := NewService("a")
+	s.ConfigureBreakers(0, time.Minute)
+	s.ConfigureRetry(RetryPolicy{MaxAttempts: 1})
+	s.RegisterProvider(p)
+
+	for range DefaultBreakerThreshold + 1 {
+		_, _ = s.Generate(context.Background(), "prompt", "a")
+	}
+	if len(s.UnavailableModels()) != 0 || p.calls != DefaultBreakerThreshold+1 {
+		t.Errorf("expected a disabled breaker to let every call through, got %d calls", p.calls)
+	}
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go"
+)
+
+// Storyboard limits. Longer plans don't fit a single scene the model can
+// write in one response.
+const (
+	maxStoryboardBeats = 20
+	maxBeatDuration    = 60 // Seconds
+)
+
+// Storyboard is the plan of an animation the code step turns into a script.
+type Storyboard struct {
+	Title      string `json:"title"`
+	Beats      []Beat `json:"beats"`
+	Warnings   string `json:"warnings"`
+	ValidInput bool   `json:"valid_input"`
+	Model      string `json:"-"` // Model that produced the storyboard, set by Service
+	Prompt     string `json:"-"` // System prompt template ID, set by Service
+	Usage      Usage  `json:"-"`
+}
+
+// Beat is one step of a storyboard.
+type Beat struct {
+	Description string   `json:"description"` // What happens on screen
+	Objects     []string `json:"objects"`     // Objects visible during the beat
+	Narration   string   `json:"narration"`   // What a narrator says during the beat
+	Duration    float64  `json:"duration"`    // Seconds
+}
+
+// StoryboardProvider is implemented by providers that can plan an animation
+// as a storyboard. Storyboards can't be produced through Generate, so models
+// without it don't support the storyboard mode.
+type StoryboardProvider interface {
+	Provider
+	GenerateStoryboard(ctx context.Context, req Request) (Storyboard, error)
+}
+
+var StoryboardSchema = GenerateSchema[Storyboard]()
+
+var StoryboardFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
+	openai.ResponseFormatJSONSchemaParam{
+		Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+		JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+			Name:        openai.F("manim_storyboard_response"),
+			Description: openai.F("A storyboard containing title, beats, warnings, and valid_input fields."),
+			Schema:      openai.F(StoryboardSchema),
+			Strict:      openai.Bool(true),
+		}),
+	},
+)
+
+// Validate checks the limits the schema can't express. It is applied to
+// storyboards of the model and to ones edited by users alike.
+func (b Storyboard) Validate() error {
+	if len(b.Beats) == 0 {
+		return errors.New("storyboard has no beats")
+	}
+	if len(b.Beats) > maxStoryboardBeats {
+		return fmt.Errorf("storyboard has %d beats, at most %d are allowed", len(b.Beats), maxStoryboardBeats)
+	}
+	var errs []error
+	for i, beat := range b.Beats {
+		if strings.TrimSpace(beat.Description) == "" {
+			errs = append(errs, fmt.Errorf("beat %d has no description", i+1))
+		}
+		if beat.Duration <= 0 || beat.Duration > maxBeatDuration {
+			errs = append(errs, fmt.Errorf("beat %d must last between 0 and %d seconds", i+1, maxBeatDuration))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StoryboardPrompt builds the prompt of the code step, asking for a script
+// that follows board.
+func StoryboardPrompt(prompt string, board Storyboard) string {
+	var b strings.Builder
+	b.WriteString("Write a Manim script for the request below that follows the storyboard beat by beat. ")
+	b.WriteString("Each beat should show its objects and last about its duration, padded with self.wait() if needed.\n\n")
+	b.WriteString("Original request:\n")
+	b.WriteString(prompt)
+	b.WriteString("\n\nStoryboard:\n")
+	if board.Title != "" {
+		b.WriteString(fmt.Sprintf("Title: %s\n", board.Title))
+	}
+	for i, beat := range board.Beats {
+		b.WriteString(fmt.Sprintf("%d. (%gs) %s\n", i+1, beat.Duration, beat.Description))
+		if len(beat.Objects) > 0 {
+			b.WriteString(fmt.Sprintf("   Objects: %s\n", strings.Join(beat.Objects, ", ")))
+		}
+		if beat.Narration != "" {
+			b.WriteString(fmt.Sprintf("   Narration: %s\n", beat.Narration))
+		}
+	}
+	return b.String()
+}
+
+// SupportsStoryboard reports whether model can plan storyboards.
+func (s *Service) SupportsStoryboard(model string) bool {
+	info, ok := s.ModelInfo(model)
+	return ok && info.Storyboard
+}
+
+// Storyboard asks model to plan prompt as a storyboard. Fallbacks without
+// storyboard support are skipped. A storyboard the model marked as valid but
+// that breaks the limits counts as a failed call.
+func (s *Service) Storyboard(ctx context.Context, prompt string, images []Image, model string, params Params) (Storyboard, error) {
+	var board Storyboard
+	storyboardPrompt := func(string) *PromptTemplate { return s.prompts.Storyboard() }
+	resp, err := s.callWith(ctx, model, params, storyboardPrompt, func(p Provider, system string, params Params) (Response, error) {
+		sp, ok := p.(StoryboardProvider)
+		if !ok {
+			return Response{}, fmt.Errorf("%s does not support storyboards: %w", p.ModelID(), errors.ErrUnsupported)
+		}
+		if err := s.requireVision(p.ModelID(), images); err != nil {
+			return Response{}, err
+		}
+		var err error
+		board, err = sp.GenerateStoryboard(ctx, Request{System: system, Prompt: prompt, Images: images, Params: params})
+		if err != nil {
+			return Response{}, err
+		}
+		if board.ValidInput {
+			if err := board.Validate(); err != nil {
+				return Response{}, fmt.Errorf("invalid storyboard: %w", err)
+			}
+		}
+		return Response{Usage: board.Usage}, nil
+	})
+	if err != nil {
+		return Storyboard{}, err
+	}
+	board.Model = resp.Model
+	board.Prompt = resp.Prompt
+	return board, nil
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoryboardValidate(t *testing.T) {
+	beat := Beat{Description: "Draw axes", Duration: 3}
+	tests := []struct {
+		name    string
+		board   Storyboard
+		wantErr string
+	}{
+		{"valid", Storyboard{Beats: []Beat{beat, beat}}, ""},
+		{"no beats", Storyboard{}, "no beats"},
+		{"too many beats", Storyboard{Beats: make([]Beat, maxStoryboardBeats+1)}, "at most 20"},
+		{"empty description", Storyboard{Beats: []Beat{beat, {Description: " ", Duration: 1}}}, "beat 2 has no description"},
+		{"zero duration", Storyboard{Beats: []Beat{{Description: "x"}}}, "beat 1 must last"},
+		{"too long", Storyboard{Beats: []Beat{{Description: "x", Duration: 61}}}, "beat 1 must last"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.board.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type storyboardProvider struct {
+	stubProvider
+}
+
+func (p *storyboardProvider) GenerateStoryboard(ctx context.Context, req Request) (Storyboard, error) {
+	if req.System != StoryboardSystemPrompt {
+		return Storyboard{}, errors.New("storyboard system prompt not used")
+	}
+	return Storyboard{Beats: []Beat{{Description: req.Prompt, Duration: 1}}, ValidInput: true}, nil
+}
+
+func TestStoryboardSkipsUnsupportedModels(t *testing.T) {
+	s := NewService("a")
+	s.ConfigureBreakers(1, time.Minute)
+	s.RegisterProvider(&stubProvider{id: "a"})
+	s.RegisterProvider(&storyboardProvider{stubProvider{id: "b"}})
+	if err := s.SetFallbacks("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.SupportsStoryboard("a") || !s.SupportsStoryboard("b") {
+		t.Error("expected only b to support storyboards")
+	}
+	board, err := s.Storyboard(context.Background(), "plan", nil, "a", Params{})
+	if err != nil {
+		t.Fatalf("Storyboard failed: %v", err)
+	}
+	if board.Model != "b" || board.Prompt != StoryboardPromptID || board.Beats[0].Description != "plan" {
+		t.Errorf("unexpected storyboard %+v", board)
+	}
+	// Lacking storyboard support is not a failure of the model
+	if len(s.UnavailableModels()) != 0 {
+		t.Errorf("unexpected open breakers %v", s.UnavailableModels())
+	}
+}
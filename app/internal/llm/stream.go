@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Chunk is an incremental update emitted while a streaming provider is
+// still producing its response.
+type Chunk struct {
+	Delta   string // Raw text received in this chunk
+	Content string // Raw text accumulated so far (a partial JSON object)
+}
+
+// StreamingProvider is implemented by providers that can report partial
+// output before the full response is available.
+type StreamingProvider interface {
+	Provider
+	GenerateStream(ctx context.Context, req Request, onChunk func(Chunk)) (Response, error)
+}
+
+// PartialCode extracts the (possibly unterminated) value of the "code" field
+// from a partial JSON response. It returns an empty string if the field has
+// not started yet.
+func PartialCode(content string) string {
+	const field = `"code"`
+	idx := strings.Index(content, field)
+	if idx < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(content[idx+len(field):], " \t\r\n")
+	if !strings.HasPrefix(rest, ":") {
+		return ""
+	}
+	rest = strings.TrimLeft(rest[1:], " \t\r\n")
+	if !strings.HasPrefix(rest, `"`) {
+		return ""
+	}
+	rest = rest[1:]
+
+	var b strings.Builder
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch c {
+		case '"':
+			return b.String()
+		case '\\':
+			if i+1 >= len(rest) {
+				return b.String()
+			}
+			i++
+			switch rest[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if i+4 >= len(rest) {
+					return b.String()
+				}
+				r, err := strconv.ParseUint(rest[i+1:i+5], 16, 32)
+				if err != nil {
+					return b.String()
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				// '"', '\\' and '/' are written as-is
+				b.WriteByte(rest[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
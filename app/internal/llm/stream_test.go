@@ -0,0 +1,50 @@
+package llm
+
+import "testing"
+
+func TestPartialCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "field not started",
+			content:  `{"description":"a circle"`,
+			expected: "",
+		},
+		{
+			name:     "value not started",
+			content:  `{"code": `,
+			expected: "",
+		},
+		{
+			name:     "unterminated value",
+			content:  `{"code":"from manim import *\nclass A(Scene`,
+			expected: "from manim import *\nclass A(Scene",
+		},
+		{
+			name:     "complete value",
+			content:  `{"code":"print(\"hi\")","description":"x"}`,
+			expected: `print("hi")`,
+		},
+		{
+			name:     "dangling escape",
+			content:  `{"code":"a\`,
+			expected: "a",
+		},
+		{
+			name:     "unicode escape",
+			content:  `{"code":"\u03c0 = 3.14"`,
+			expected: "π = 3.14",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PartialCode(tt.content); got != tt.expected {
+				t.Errorf("PartialCode(%q) = %q, want %q", tt.content, got, tt.expected)
+			}
+		})
+	}
+}
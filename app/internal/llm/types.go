@@ -8,21 +8,75 @@ import (
 )
 
 type Provider interface {
-	Generate(ctx context.Context, prompt string) (Response, error)
+	Generate(ctx context.Context, req Request) (Response, error)
 	ModelID() string
 }
 
+// Request is a single generation call to a provider.
+type Request struct {
+	System   string    // Rendered system prompt, DefaultSystemPrompt if empty
+	Examples []Message // Few-shot turns sent before the prompt
+	Prompt   string    // User prompt
+	Images   []Image   // Attached to the user prompt, for vision models only
+	Params   Params    // Overridden generation parameters
+}
+
+// Messages returns the few-shot examples followed by the user prompt.
+func (r Request) Messages() []Message {
+	msgs := make([]Message, 0, len(r.Examples)+1)
+	msgs = append(msgs, r.Examples...)
+	return append(msgs, Message{Role: RoleUser, Content: r.Prompt, Images: r.Images})
+}
+
+// SystemPrompt returns the system prompt to send with the request.
+func (r Request) SystemPrompt() string {
+	if r.System == "" {
+		return DefaultSystemPrompt
+	}
+	return r.System
+}
+
 type Response struct {
-	Code        string `json:"code"`
-	Description string `json:"description"`
-	Warnings    string `json:"warnings"`
-	SceneName   string `json:"scene_name"`
-	ValidInput  bool   `json:"valid_input"`
+	Code        string          `json:"code"`
+	Description string          `json:"description"`
+	Warnings    string          `json:"warnings"`
+	SceneName   string          `json:"scene_name"`
+	ValidInput  bool            `json:"valid_input"`
+	Narration   []NarrationLine `json:"narration"` // Empty unless narration was asked for
+	Model       string          `json:"-"`         // Model that produced the response, set by Service
+	Prompt      string          `json:"-"`         // ID of the system prompt template used, set by Service
+	Usage       Usage           `json:"-"`         // Tokens billed for the call that produced the response
+	Cached      bool            `json:"-"`         // Served from the response cache without calling a provider
+
+	cacheKey string // Key Service.Remember caches the response under, empty if it must not be
+}
+
+// NarrationLine is a line of the narration track, timed against the
+// animations of the scene.
+type NarrationLine struct {
+	Start float64 `json:"start"` // Seconds from the beginning of the video
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Usage counts the tokens a provider billed for a call.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+	}
 }
 
 type ModelsResponse struct {
-	Models       []string `json:"models"`
-	DefaultModel string   `json:"default_model"`
+	Models       []string    `json:"models"`
+	Details      []ModelInfo `json:"details"`     // Capabilities of each model, in the order of Models
+	Unavailable  []string    `json:"unavailable"` // Models whose circuit breaker is open
+	DefaultModel string      `json:"default_model"`
 }
 
 func GenerateSchema[T any]() any {
@@ -38,7 +92,7 @@ var ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion]
 		Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
 		JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
 			Name:        openai.F("manim_script_response"),
-			Description: openai.F("A response containing code, description, warnings, scene_name, and valid_input fields."),
+			Description: openai.F("A response containing code, description, warnings, scene_name, valid_input, and narration fields."),
 			Schema:      openai.F(ManimSchema),
 			Strict:      openai.Bool(true),
 		}),
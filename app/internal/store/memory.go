@@ -0,0 +1,66 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// Memory keeps the history in memory. It is meant for tests and local
+// development; everything is lost on restart.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]Entry)}
+}
+
+func (m *Memory) Save(_ context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[e.ID] = e
+	return nil
+}
+
+func (m *Memory) Get(_ context.Context, sessionID, id string) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[id]
+	if !ok || e.SessionID != sessionID {
+		return Entry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+func (m *Memory) List(_ context.Context, sessionID string, page Page) ([]Entry, int, error) {
+	m.mu.Lock()
+	var session []Entry
+	for _, e := range m.entries {
+		if e.SessionID == sessionID {
+			session = append(session, e)
+		}
+	}
+	m.mu.Unlock()
+
+	slices.SortFunc(session, newestFirst)
+	total := len(session)
+	start := min(max(page.Offset, 0), total)
+	end := total
+	if page.Limit > 0 {
+		end = min(start+page.Limit, total)
+	}
+	return session[start:end], total, nil
+}
+
+func (m *Memory) Close() error { return nil }
+
+// newestFirst orders entries like the SQLite listing.
+func newestFirst(a, b Entry) int {
+	if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.ID, a.ID)
+}
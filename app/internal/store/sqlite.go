@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS history (
+	id            TEXT PRIMARY KEY,
+	session_id    TEXT NOT NULL,
+	kind          TEXT NOT NULL,
+	status        TEXT NOT NULL,
+	prompt        TEXT NOT NULL DEFAULT '',
+	model         TEXT NOT NULL DEFAULT '',
+	script        TEXT NOT NULL DEFAULT '',
+	error         TEXT NOT NULL DEFAULT '',
+	video_key     TEXT NOT NULL DEFAULT '',
+	subtitles_key TEXT NOT NULL DEFAULT '',
+	has_audio     INTEGER NOT NULL DEFAULT 0,
+	created_at    INTEGER NOT NULL, -- Unix nanoseconds
+	updated_at    INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS history_session ON history (session_id, created_at DESC, id DESC);
+`
+
+const columns = `id, session_id, kind, status, prompt, model, script, error, video_key, subtitles_key, has_audio, created_at, updated_at`
+
+// SQLite keeps the history in an SQLite database file.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database at path, creating it and its directory if
+// needed.
+func OpenSQLite(path string) (*SQLite, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create history directory: %w", err)
+		}
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	// SQLite serializes writers anyway; one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create history schema: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Save(ctx context.Context, e Entry) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO history (`+columns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			session_id = excluded.session_id,
+			kind = excluded.kind,
+			status = excluded.status,
+			prompt = excluded.prompt,
+			model = excluded.model,
+			script = excluded.script,
+			error = excluded.error,
+			video_key = excluded.video_key,
+			subtitles_key = excluded.subtitles_key,
+			has_audio = excluded.has_audio,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at`,
+		e.ID, e.SessionID, e.Kind, e.Status, e.Prompt, e.Model, e.Script, e.Error,
+		e.VideoKey, e.SubtitlesKey, e.HasAudio, e.CreatedAt.UnixNano(), e.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save history entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Get(ctx context.Context, sessionID, id string) (Entry, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+columns+` FROM history WHERE id = ? AND session_id = ?`, id, sessionID)
+	e, err := scanEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to get history entry: %w", err)
+	}
+	return e, nil
+}
+
+func (s *SQLite) List(ctx context.Context, sessionID string, page Page) ([]Entry, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM history WHERE session_id = ?`, sessionID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count history entries: %w", err)
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT `+columns+` FROM history WHERE session_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, sessionID, limit, max(page.Offset, 0))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list history entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read history entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list history entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func scanEntry(row interface{ Scan(...any) error }) (Entry, error) {
+	var e Entry
+	var createdAt, updatedAt int64
+	err := row.Scan(&e.ID, &e.SessionID, &e.Kind, &e.Status, &e.Prompt, &e.Model, &e.Script, &e.Error,
+		&e.VideoKey, &e.SubtitlesKey, &e.HasAudio, &createdAt, &updatedAt)
+	if err != nil {
+		return Entry{}, err
+	}
+	e.CreatedAt = time.Unix(0, createdAt)
+	e.UpdatedAt = time.Unix(0, updatedAt)
+	return e, nil
+}
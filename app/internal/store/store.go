@@ -0,0 +1,49 @@
+// Package store keeps the history of the jobs of each session so users can
+// get back to past prompts, scripts and videos.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("history entry not found")
+
+// Entry is a generate, refine or compile job as kept in the history of its
+// session.
+type Entry struct {
+	ID           string // Job ID
+	SessionID    string
+	Kind         string // generate, refine or compile
+	Status       string // Last known status of the job
+	Prompt       string // The prompt or, for refinements, the instruction
+	Model        string
+	Script       string
+	Error        string // Why the job failed
+	VideoKey     string // S3 key of the rendered video
+	SubtitlesKey string // S3 key of the subtitles, set when the script came with narration
+	HasAudio     bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Page selects entries of a listing, newest first. A zero Limit selects
+// all the entries after Offset.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Store is where the history is kept. Entries are always looked up within
+// their session.
+type Store interface {
+	// Save inserts e or replaces the entry with the same ID.
+	Save(ctx context.Context, e Entry) error
+	// Get returns the entry id of the session, or ErrNotFound.
+	Get(ctx context.Context, sessionID, id string) (Entry, error)
+	// List returns a page of the entries of the session and how many there
+	// are in total.
+	List(ctx context.Context, sessionID string, page Page) ([]Entry, int, error)
+	Close() error
+}
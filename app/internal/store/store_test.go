@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(t *testing.T) Store { return NewMemory() },
+		"sqlite": func(t *testing.T) Store {
+			s, err := OpenSQLite(filepath.Join(t.TempDir(), "history", "history.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := open(t)
+			defer s.Close()
+			testStore(t, s)
+		})
+	}
+}
+
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	for i, id := range []string{"a", "b", "c"} {
+		e := Entry{ID: id, SessionID: "s1", Kind: "generate", Status: "generating", Prompt: "draw a circle", CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+		e.UpdatedAt = e.CreatedAt
+		if err := s.Save(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(ctx, Entry{ID: "other", SessionID: "s2", Kind: "compile", Status: "compiling", CreatedAt: start, UpdatedAt: start}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := Entry{ID: "b", SessionID: "s1", Kind: "generate", Status: "succeeded", Prompt: "draw a circle", Model: "gpt-4o",
+		Script: "class A(Scene): pass", VideoKey: "manim_outputs/s1/1.mp4", SubtitlesKey: "manim_outputs/s1/2.vtt", HasAudio: true,
+		CreatedAt: start.Add(time.Minute), UpdatedAt: start.Add(2 * time.Minute)}
+	if err := s.Save(ctx, done); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get(ctx, "s1", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(done.CreatedAt) || !got.UpdatedAt.Equal(done.UpdatedAt) {
+		t.Errorf("got times %s/%s, want %s/%s", got.CreatedAt, got.UpdatedAt, done.CreatedAt, done.UpdatedAt)
+	}
+	got.CreatedAt, got.UpdatedAt = done.CreatedAt, done.UpdatedAt
+	if got != done {
+		t.Errorf("Get() = %+v, want %+v", got, done)
+	}
+	if _, err := s.Get(ctx, "s2", "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of another session's entry = %v, want ErrNotFound", err)
+	}
+
+	tests := []struct {
+		page Page
+		want []string
+	}{
+		{Page{Limit: 2}, []string{"c", "b"}},
+		{Page{Limit: 2, Offset: 2}, []string{"a"}},
+		{Page{Offset: 1}, []string{"b", "a"}},
+		{Page{Limit: 2, Offset: 5}, nil},
+	}
+	for _, tt := range tests {
+		entries, total, err := s.List(ctx, "s1", tt.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		if total != 3 || !slices.Equal(ids, tt.want) {
+			t.Errorf("List(%+v) = %v of %d, want %v of 3", tt.page, ids, total, tt.want)
+		}
+	}
+}
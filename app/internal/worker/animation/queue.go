@@ -23,8 +23,9 @@ const (
 type Result struct {
 	Type      ResultType
 	SessionID string
-	VideoURL  string // filled only if Type is Success
-	Error     error  // filled only if Type is Error
+	JobID     string                // copied from the compile request, may be empty
+	Video     events.CompileSuccess // filled only if Type is Success
+	Error     error                 // filled only if Type is Error
 }
 
 type TaskMessage struct {
@@ -34,18 +35,20 @@ type TaskMessage struct {
 	Valid bool                   //Is valid
 }
 
-func NewSuccessResult(sessionID, videoURL string) *Result {
+func NewSuccessResult(sessionID, jobID string, video events.CompileSuccess) *Result {
 	return &Result{
 		Type:      ResultTypeSuccess,
 		SessionID: sessionID,
-		VideoURL:  videoURL,
+		JobID:     jobID,
+		Video:     video,
 	}
 }
 
-func NewErrorResult(sessionID string, err error) *Result {
+func NewErrorResult(sessionID, jobID string, err error) *Result {
 	return &Result{
 		Type:      ResultTypeError,
 		SessionID: sessionID,
+		JobID:     jobID,
 		Error:     err,
 	}
 }
@@ -103,17 +106,17 @@ func (q *Queue) PublishResult(ctx context.Context, result *Result) error {
 	switch result.Type {
 
 	case ResultTypeSuccess:
-		event := events.NewCompileSuccess(result.SessionID, result.VideoURL)
+		event := events.NewCompileSuccess(result.SessionID, result.Video).WithJobID(result.JobID)
 		return q.queue.SendMessage(ctx, event)
 	case ResultTypeError:
-		return q.publishError(ctx, result.SessionID, result.Error)
+		return q.publishError(ctx, result.SessionID, result.JobID, result.Error)
 	default:
 		q.log.Warn("Unknown result type", "type", result.Type, "result", result)
 		return nil
 	}
 }
 
-func (q *Queue) publishError(ctx context.Context, sessionID string, err error) error {
+func (q *Queue) publishError(ctx context.Context, sessionID, jobID string, err error) error {
 	var execErr *manimexec.ExecutionError
 
 	if !errors.As(err, &execErr) {
@@ -121,7 +124,7 @@ func (q *Queue) publishError(ctx context.Context, sessionID string, err error) e
 			sessionID,
 			err.Error(),
 			"", "", 0,
-		)
+		).WithJobID(jobID)
 		return q.queue.SendMessage(ctx, event)
 	}
 
@@ -133,7 +136,7 @@ func (q *Queue) publishError(ctx context.Context, sessionID string, err error) e
 			execErr.Stdout,
 			execErr.Stderr,
 			execErr.Line,
-		)
+		).WithJobID(jobID)
 		return q.queue.SendMessage(ctx, event)
 
 	case manimexec.ErrorKindSecurity:
@@ -143,7 +146,7 @@ func (q *Queue) publishError(ctx context.Context, sessionID string, err error) e
 			execErr.Stdout,
 			execErr.Stderr,
 			execErr.Line,
-		)
+		).WithJobID(jobID)
 		return q.queue.SendMessage(ctx, event)
 
 	case manimexec.ErrorKindTimeout:
@@ -153,7 +156,7 @@ func (q *Queue) publishError(ctx context.Context, sessionID string, err error) e
 			execErr.Stdout,
 			execErr.Stderr,
 			execErr.Line,
-		)
+		).WithJobID(jobID)
 		return q.queue.SendMessage(ctx, event)
 
 	default:
@@ -163,7 +166,7 @@ func (q *Queue) publishError(ctx context.Context, sessionID string, err error) e
 			execErr.Stdout,
 			execErr.Stderr,
 			execErr.Line,
-		)
+		).WithJobID(jobID)
 		return q.queue.SendMessage(ctx, event)
 	}
 }
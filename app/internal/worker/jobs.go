@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"manimatic/internal/api/events"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cancelPollInterval is how often the cancellation of running compilations
+// is checked. Each check is a single listing, however many jobs run.
+const cancelPollInterval = 2 * time.Second
+
+// CancelStore tells whether the API canceled a compilation. The API marks a
+// canceled job under events.CancelKey, where every worker sees it.
+type CancelStore interface {
+	Exists(ctx context.Context, key string) (bool, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// jobRegistry tracks the compilations running on this worker so they can be
+// canceled by job ID. Without a store nothing is ever canceled.
+type jobRegistry struct {
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+	store   CancelStore
+}
+
+func newJobRegistry(store CancelStore) *jobRegistry {
+	return &jobRegistry{
+		running: make(map[string]context.CancelFunc),
+		store:   store,
+	}
+}
+
+// start derives the context of a compilation from parent. It returns false if
+// the job was canceled before it started.
+func (r *jobRegistry) start(parent context.Context, jobID string) (context.Context, context.CancelFunc, bool, error) {
+	ctx, cancel := context.WithCancel(parent)
+	if jobID == "" {
+		return ctx, cancel, true, nil
+	}
+
+	canceled, err := r.canceled(parent, jobID)
+	if canceled {
+		cancel()
+		return ctx, cancel, false, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running[jobID] = cancel
+	return ctx, cancel, true, err
+}
+
+// finish forgets a compilation once it completed.
+func (r *jobRegistry) finish(jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.running, jobID)
+}
+
+// poll stops the running compilations that were canceled and returns their
+// job IDs. The markers of all jobs are listed at once rather than checked
+// job by job.
+func (r *jobRegistry) poll(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	idle := len(r.running) == 0
+	r.mu.Unlock()
+	if idle || r.store == nil {
+		return nil, nil
+	}
+
+	keys, err := r.store.List(ctx, events.CancelPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var stopped []string
+	for _, key := range keys {
+		id := strings.TrimPrefix(key, events.CancelPrefix)
+		if r.cancel(id) {
+			stopped = append(stopped, id)
+		}
+	}
+	return stopped, nil
+}
+
+// cancel stops the compilation of jobID. It reports whether the job was
+// running.
+func (r *jobRegistry) cancel(jobID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cancel, ok := r.running[jobID]
+	if ok {
+		cancel()
+		delete(r.running, jobID)
+	}
+	return ok
+}
+
+func (r *jobRegistry) canceled(ctx context.Context, jobID string) (bool, error) {
+	if r.store == nil {
+		return false, nil
+	}
+	return r.store.Exists(ctx, events.CancelKey(jobID))
+}
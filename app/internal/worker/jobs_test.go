@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"manimatic/internal/api/events"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// markers is a cancel store shared by several workers.
+type markers struct {
+	mu    sync.Mutex
+	keys  map[string]bool
+	lists int
+}
+
+func (m *markers) mark(jobID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[events.CancelKey(jobID)] = true
+}
+
+func (m *markers) Exists(_ context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.keys[key], nil
+}
+
+func (m *markers) List(_ context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lists++
+	var keys []string
+	for key := range m.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func TestJobRegistry(t *testing.T) {
+	store := &markers{keys: make(map[string]bool)}
+	a, b := newJobRegistry(store), newJobRegistry(store)
+	ctx := context.Background()
+
+	ctxA, cancelA, _, _ := a.start(ctx, "a")
+	defer cancelA()
+	ctxB, cancelB, ok, err := b.start(ctx, "b")
+	defer cancelB()
+	if !ok || err != nil {
+		t.Fatalf("expected job to start, got %t, %v", ok, err)
+	}
+
+	// Whichever worker runs the job stops it
+	store.mark("b")
+	if stopped, err := a.poll(ctx); len(stopped) != 0 || err != nil {
+		t.Errorf("worker a stopped %v, %v, want nothing", stopped, err)
+	}
+	if stopped, err := b.poll(ctx); !slices.Equal(stopped, []string{"b"}) || err != nil {
+		t.Errorf("worker b stopped %v, %v, want [b]", stopped, err)
+	}
+	if ctxB.Err() == nil || ctxA.Err() != nil {
+		t.Errorf("expected only job b to be canceled, got a: %v, b: %v", ctxA.Err(), ctxB.Err())
+	}
+
+	// One listing per poll, none without running jobs
+	b.poll(ctx)
+	if store.lists != 2 {
+		t.Errorf("listed the markers %d times, want 2", store.lists)
+	}
+
+	// A cancel can overtake the compile request in the queue
+	store.mark("queued")
+	if _, cancel, ok, _ := a.start(ctx, "queued"); ok {
+		t.Error("expected canceled job not to start")
+	} else {
+		cancel()
+	}
+}
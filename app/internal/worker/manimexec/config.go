@@ -23,7 +23,8 @@ const (
 )
 
 var (
-	ErrScriptTooLarge   = errors.New("script exceeds maximum size limit")
-	ErrExecutionTimeout = errors.New("script execution timed out")
-	ErrOutputTooLarge   = errors.New("output exceeds maximum size")
+	ErrScriptTooLarge    = errors.New("script exceeds maximum size limit")
+	ErrExecutionTimeout  = errors.New("script execution timed out")
+	ErrExecutionCanceled = errors.New("script execution canceled")
+	ErrOutputTooLarge    = errors.New("output exceeds maximum size")
 )
@@ -3,6 +3,7 @@ package manimexec
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"manimatic/internal/config"
 	"manimatic/internal/worker/manimexec/security"
@@ -171,6 +172,9 @@ func (e *Executor) runManimProcess(ctx context.Context, scriptPath, outputPath s
 		// Kill the process group
 		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 		<-done // Wait for the process to be killed
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil, newCanceledError()
+		}
 		return nil, newTimeoutError()
 
 	case err := <-done:
@@ -25,6 +25,7 @@ const (
 	ErrorKindTimeout                      // Execution timed out
 	ErrorKindCompilation                  // Manim compilation failed
 	ErrorKindSystem                       // System-level error (IO, etc)
+	ErrorKindCanceled                     // Execution was canceled by the API
 )
 
 func (k ErrorKind) String() string {
@@ -39,6 +40,8 @@ func (k ErrorKind) String() string {
 		return "Compilation Error"
 	case ErrorKindSystem:
 		return "System Error"
+	case ErrorKindCanceled:
+		return "Canceled"
 	default:
 		return "Unknown Error"
 	}
@@ -84,6 +87,14 @@ func newTimeoutError() *ExecutionError {
 	}
 }
 
+func newCanceledError() *ExecutionError {
+	return &ExecutionError{
+		Kind:    ErrorKindCanceled,
+		Message: "Script execution was canceled",
+		Cause:   ErrExecutionCanceled,
+	}
+}
+
 func newCompilationError(message string, stdout, stderr string, cause error) *ExecutionError {
 	return &ExecutionError{
 		Kind:    ErrorKindCompilation,
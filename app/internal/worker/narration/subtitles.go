@@ -0,0 +1,99 @@
+// Package narration turns the narration track of a script into a subtitle
+// file and, optionally, a voice-over mixed into the rendered video.
+package narration
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"manimatic/internal/api/events"
+	"math"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Subtitle formats
+const (
+	FormatVTT = "vtt"
+	FormatSRT = "srt"
+)
+
+// Lines returns the lines of track that can be shown, ordered by start time.
+// Lines without text or ending before they start are dropped.
+func Lines(track []events.NarrationLine) []events.NarrationLine {
+	lines := make([]events.NarrationLine, 0, len(track))
+	for _, l := range track {
+		l.Text = cleanText(l.Text)
+		if l.Text == "" || l.Start < 0 || l.End <= l.Start {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	slices.SortStableFunc(lines, func(a, b events.NarrationLine) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return lines
+}
+
+// cleanText joins the lines of text, dropping the blank ones that would end
+// a cue early.
+func cleanText(text string) string {
+	var parts []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// WriteSubtitles writes lines, as returned by Lines, as a subtitle file in
+// format.
+func WriteSubtitles(w io.Writer, format string, lines []events.NarrationLine) error {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case FormatVTT:
+		bw.WriteString("WEBVTT\n")
+		for _, l := range lines {
+			fmt.Fprintf(bw, "\n%s --> %s\n%s\n", timestamp(l.Start, '.'), timestamp(l.End, '.'), escapeVTT(l.Text))
+		}
+	case FormatSRT:
+		for i, l := range lines {
+			if i > 0 {
+				bw.WriteString("\n")
+			}
+			fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n", i+1, timestamp(l.Start, ','), timestamp(l.End, ','), l.Text)
+		}
+	default:
+		return fmt.Errorf("unknown subtitle format %q", format)
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes lines as a subtitle file in format at path.
+func WriteFile(path, format string, lines []events.NarrationLine) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create subtitles: %w", err)
+	}
+	if err := WriteSubtitles(f, format, lines); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// timestamp formats seconds as hh:mm:ss followed by sep and milliseconds.
+func timestamp(seconds float64, sep byte) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, sep, ms%1000)
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeVTT escapes the characters WebVTT cue text reserves for markup.
+func escapeVTT(text string) string {
+	return vttEscaper.Replace(text)
+}
@@ -0,0 +1,75 @@
+package narration
+
+import (
+	"manimatic/internal/api/events"
+	"strings"
+	"testing"
+)
+
+func TestWriteSubtitles(t *testing.T) {
+	track := []events.NarrationLine{
+		{Start: 2, End: 4.25, Text: "Watch it turn\n\ninto a <square>."},
+		{Start: 0, End: 2, Text: "We start with a circle."},
+		{Start: 5, End: 5, Text: "Empty slot"},
+		{Start: 6, End: 7, Text: "  "},
+		{Start: 3725.5, End: 3726, Text: "An hour later"},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatVTT, `WEBVTT
+
+00:00:00.000 --> 00:00:02.000
+We start with a circle.
+
+00:00:02.000 --> 00:00:04.250
+Watch it turn
+into a &lt;square&gt;.
+
+01:02:05.500 --> 01:02:06.000
+An hour later
+`},
+		{FormatSRT, `1
+00:00:00,000 --> 00:00:02,000
+We start with a circle.
+
+2
+00:00:02,000 --> 00:00:04,250
+Watch it turn
+into a <square>.
+
+3
+01:02:05,500 --> 01:02:06,000
+An hour later
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b strings.Builder
+			if err := WriteSubtitles(&b, tt.format, Lines(track)); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+
+	if err := WriteSubtitles(&strings.Builder{}, "ass", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestMuxArgs(t *testing.T) {
+	lines := []events.NarrationLine{{Start: 0, End: 2}, {Start: 2.5, End: 4}}
+	args := muxArgs("in.mp4", "out.mp4", []string{"n0.wav", "n1.wav"}, lines)
+
+	got := strings.Join(args, " ")
+	want := "-y -loglevel error -i in.mp4 -i n0.wav -i n1.wav -filter_complex " +
+		"[1:a]adelay=0:all=1[a0];[2:a]adelay=2500:all=1[a1];[a0][a1]amix=inputs=2:duration=longest:normalize=0,apad[narration] " +
+		"-map 0:v -map [narration] -c:v copy -c:a aac -shortest out.mp4"
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
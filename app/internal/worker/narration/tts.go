@@ -0,0 +1,122 @@
+package narration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"manimatic/internal/api/events"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// TTS engines
+const (
+	EngineNone   = "none"
+	EngineEspeak = "espeak-ng"
+	EnginePiper  = "piper"
+)
+
+// Synthesizer reads text aloud into a WAV file.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text, wavPath string) error
+}
+
+// NewSynthesizer returns the synthesizer of engine, or nil for EngineNone.
+// The voice is an espeak-ng voice name, optional, or the path of a piper
+// voice model.
+func NewSynthesizer(engine, voice string) (Synthesizer, error) {
+	switch engine {
+	case "", EngineNone:
+		return nil, nil
+	case EngineEspeak:
+		return espeak{voice: voice}, nil
+	case EnginePiper:
+		if voice == "" {
+			return nil, errors.New("piper needs a voice model")
+		}
+		return piper{model: voice}, nil
+	default:
+		return nil, fmt.Errorf("unknown TTS engine %q", engine)
+	}
+}
+
+type espeak struct {
+	voice string
+}
+
+func (e espeak) Synthesize(ctx context.Context, text, wavPath string) error {
+	args := []string{"--stdin", "-w", wavPath}
+	if e.voice != "" {
+		args = append(args, "-v", e.voice)
+	}
+	return run(ctx, strings.NewReader(text), "espeak-ng", args...)
+}
+
+type piper struct {
+	model string
+}
+
+func (p piper) Synthesize(ctx context.Context, text, wavPath string) error {
+	// Piper reads one utterance per line
+	text = strings.ReplaceAll(text, "\n", " ")
+	return run(ctx, strings.NewReader(text), "piper", "--model", p.model, "--output_file", wavPath)
+}
+
+// Dub reads lines, as returned by Lines, with synth and mixes them into the
+// video at videoPath, each starting at its time. The result is written to
+// outPath; the speech files are kept next to it.
+func Dub(ctx context.Context, synth Synthesizer, videoPath, outPath string, lines []events.NarrationLine) error {
+	if len(lines) == 0 {
+		return errors.New("no narration to dub")
+	}
+	wavs := make([]string, len(lines))
+	for i, l := range lines {
+		wavs[i] = filepath.Join(filepath.Dir(outPath), fmt.Sprintf("narration_%d.wav", i))
+		if err := synth.Synthesize(ctx, l.Text, wavs[i]); err != nil {
+			return fmt.Errorf("failed to synthesize line %d: %w", i+1, err)
+		}
+	}
+	if err := run(ctx, nil, "ffmpeg", muxArgs(videoPath, outPath, wavs, lines)...); err != nil {
+		return fmt.Errorf("failed to mux narration: %w", err)
+	}
+	return nil
+}
+
+// muxArgs builds the ffmpeg arguments that delay each speech file to the
+// start of its line, mix them and add the mix as the audio of the video. The
+// mix is padded with silence so the video is never cut short.
+func muxArgs(videoPath, outPath string, wavs []string, lines []events.NarrationLine) []string {
+	args := []string{"-y", "-loglevel", "error", "-i", videoPath}
+	var graph strings.Builder
+	for i, wav := range wavs {
+		args = append(args, "-i", wav)
+		fmt.Fprintf(&graph, "[%d:a]adelay=%d:all=1[a%d];", i+1, int64(lines[i].Start*1000), i)
+	}
+	for i := range wavs {
+		fmt.Fprintf(&graph, "[a%d]", i)
+	}
+	fmt.Fprintf(&graph, "amix=inputs=%d:duration=longest:normalize=0,apad[narration]", len(wavs))
+
+	return append(args,
+		"-filter_complex", graph.String(),
+		"-map", "0:v", "-map", "[narration]",
+		"-c:v", "copy", "-c:a", "aac",
+		"-shortest",
+		outPath,
+	)
+}
+
+func run(ctx context.Context, stdin *strings.Reader, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
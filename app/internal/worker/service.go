@@ -4,18 +4,25 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"manimatic/internal/api/events"
 	"manimatic/internal/config"
 	"manimatic/internal/worker/animation"
 	"manimatic/internal/worker/manimexec"
+	"manimatic/internal/worker/narration"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
+// narrationTimeout bounds the speech synthesis and muxing of a video.
+const narrationTimeout = time.Minute
+
 type VideoStorage interface {
-	UploadAndPresign(ctx context.Context, outputPath string, sessionId string) (string, error)
+	UploadAndPresign(ctx context.Context, outputPath string, sessionId string) (key, url string, err error)
 }
 
 type WorkerService struct {
@@ -27,11 +34,27 @@ type WorkerService struct {
 	cancelContext context.Context
 	cancelFunc    context.CancelFunc
 	executer      *manimexec.Executor
+	jobs          *jobRegistry
+	synth         narration.Synthesizer // nil when narration isn't dubbed
+}
+
+// narratedMedia is what the narration of a script added to its video.
+type narratedMedia struct {
+	subtitlesPath string
+	hasAudio      bool
 }
 
-func NewWorkerService(cfg *config.Config, queue *animation.Queue, storage VideoStorage, log *slog.Logger) (*WorkerService, error) {
+func NewWorkerService(cfg *config.Config, queue *animation.Queue, storage VideoStorage, cancels CancelStore, log *slog.Logger) (*WorkerService, error) {
+
+	synth, err := narration.NewSynthesizer(cfg.Worker.TTSEngine, cfg.Worker.TTSVoice)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	if cancels == nil {
+		log.Warn("no cancel store, canceled compilations run to completion")
+	}
 
 	workerPool := NewWorkerPool(cfg.Processing.MaxConcurrency, log)
 
@@ -44,6 +67,8 @@ func NewWorkerService(cfg *config.Config, queue *animation.Queue, storage VideoS
 		cancelContext: ctx,
 		cancelFunc:    cancel,
 		executer:      manimexec.MustNewExecutor(cfg),
+		jobs:          newJobRegistry(cancels),
+		synth:         synth,
 	}, nil
 }
 
@@ -60,6 +85,7 @@ func (ws *WorkerService) Run() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	ws.startMessageLoop()
+	ws.startCancelWatch()
 
 	<-sigChan
 	ws.log.Info("Shutting down gracefully...")
@@ -91,7 +117,6 @@ func (ws *WorkerService) fetchAndSubmitMessage() {
 		// no message available yet
 		return
 	}
-
 	ws.workerPool.Submit(Task{
 		event:          t.E,
 		compileRequest: t.R,
@@ -99,22 +124,93 @@ func (ws *WorkerService) fetchAndSubmitMessage() {
 	})
 }
 
+// startCancelWatch kills the running compilations the API canceled.
+func (ws *WorkerService) startCancelWatch() {
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ws.cancelContext.Done():
+				return
+			case <-ticker.C:
+			}
+			stopped, err := ws.jobs.poll(ws.cancelContext)
+			if err != nil && ws.cancelContext.Err() == nil {
+				ws.log.Error("failed to check canceled compilations", "error", err)
+			}
+			for _, jobID := range stopped {
+				ws.log.Info("compilation canceled", "job_id", jobID)
+			}
+		}
+	}()
+}
+
 func (ws *WorkerService) processTask(task Task) error {
-	res, err := ws.executer.ExecuteScript(ws.cancelContext, task.compileRequest.Script, task.event.SessionID)
+	ctx, cancel, ok, err := ws.jobs.start(ws.cancelContext, task.event.JobID)
+	defer cancel()
+	defer ws.jobs.finish(task.event.JobID)
+	if err != nil {
+		ws.log.Error("failed to check whether the compilation was canceled", "error", err, "job_id", task.event.JobID)
+	}
+	if !ok {
+		ws.log.Info("skipping canceled compilation", "session_id", task.event.SessionID, "job_id", task.event.JobID)
+		return ws.queue.DeleteTask(ws.cancelContext, task.h)
+	}
+
+	res, err := ws.executer.ExecuteScript(ctx, task.compileRequest.Script, task.event.SessionID)
 	if err != nil {
 		return ws.handleExecutionError(task, err)
 	}
-	ws.handleSuccessfulExecution(task, res)
+	media := ws.narrate(ctx, task, res)
+	ws.handleSuccessfulExecution(task, res, media)
 	return nil
 }
 
+// narrate writes the subtitles of the narration of task and, when a TTS
+// engine is configured, dubs the narration into the video. Narration is best
+// effort, the video is delivered without whatever part of it failed.
+func (ws *WorkerService) narrate(ctx context.Context, task Task, res *manimexec.ExecutionResult) narratedMedia {
+	lines := narration.Lines(task.compileRequest.Narration)
+	if len(lines) == 0 {
+		return narratedMedia{}
+	}
+
+	var media narratedMedia
+	path := filepath.Join(res.WorkingDir, "subtitles."+ws.config.Worker.SubtitlesFormat)
+	if err := narration.WriteFile(path, ws.config.Worker.SubtitlesFormat, lines); err != nil {
+		ws.log.Error("failed to write subtitles", "error", err, "job_id", task.event.JobID)
+	} else {
+		media.subtitlesPath = path
+	}
+
+	if ws.synth == nil || filepath.Ext(res.OutputPath) != ".mp4" {
+		return media
+	}
+	ctx, cancel := context.WithTimeout(ctx, narrationTimeout)
+	defer cancel()
+	dubbed := filepath.Join(res.WorkingDir, "narrated.mp4")
+	if err := narration.Dub(ctx, ws.synth, res.OutputPath, dubbed, lines); err != nil {
+		ws.log.Error("failed to dub narration", "error", err, "job_id", task.event.JobID)
+		return media
+	}
+	res.OutputPath = dubbed
+	media.hasAudio = true
+	return media
+}
+
 func (ws *WorkerService) handleExecutionError(task Task, err error) error {
+	if errors.Is(err, manimexec.ErrExecutionCanceled) && ws.cancelContext.Err() == nil {
+		// The API superseded the job and drops its results anyway
+		ws.log.Info("killed canceled compilation", "session_id", task.event.SessionID, "job_id", task.event.JobID)
+		return ws.queue.DeleteTask(ws.cancelContext, task.h)
+	}
 	ws.log.Error("failed to execute manim script", "error", err.Error())
 	go ws.cleanupFailedTask(task, err)
 	return nil
 }
 func (ws *WorkerService) cleanupFailedTask(task Task, err error) {
-	if err := ws.queue.PublishResult(ws.cancelContext, animation.NewErrorResult(task.event.SessionID, err)); err != nil {
+	if err := ws.queue.PublishResult(ws.cancelContext, animation.NewErrorResult(task.event.SessionID, task.event.JobID, err)); err != nil {
 		ws.log.Error("Failed to enqueue error event", "error", err)
 	}
 	if err := ws.queue.DeleteTask(ws.cancelContext, task.h); err != nil {
@@ -122,11 +218,11 @@ func (ws *WorkerService) cleanupFailedTask(task Task, err error) {
 	}
 }
 
-func (ws *WorkerService) handleSuccessfulExecution(task Task, res *manimexec.ExecutionResult) {
-	go ws.processSuccess(task, res)
+func (ws *WorkerService) handleSuccessfulExecution(task Task, res *manimexec.ExecutionResult, media narratedMedia) {
+	go ws.processSuccess(task, res, media)
 }
 
-func (ws *WorkerService) processSuccess(task Task, res *manimexec.ExecutionResult) {
+func (ws *WorkerService) processSuccess(task Task, res *manimexec.ExecutionResult, media narratedMedia) {
 	// delete task first
 	if err := ws.queue.DeleteTask(ws.cancelContext, task.h); err != nil {
 		ws.log.Error("failed to delete task", "error", err, "handle", task.h)
@@ -134,14 +230,28 @@ func (ws *WorkerService) processSuccess(task Task, res *manimexec.ExecutionResul
 	}
 
 	// upload and get url
-	url, err := ws.storage.UploadAndPresign(ws.cancelContext, res.OutputPath, task.event.SessionID)
+	var video events.CompileSuccess
+	var err error
+	video.VideoKey, video.VideoURL, err = ws.storage.UploadAndPresign(ws.cancelContext, res.OutputPath, task.event.SessionID)
 	if err != nil {
 		ws.log.Error("failed to upload and presign", "error", err)
 		return
 	}
 
+	// the subtitles are uploaded next to the video, the video is useful without them
+	if media.subtitlesPath != "" {
+		key, url, err := ws.storage.UploadAndPresign(ws.cancelContext, media.subtitlesPath, task.event.SessionID)
+		if err != nil {
+			ws.log.Error("failed to upload subtitles", "error", err)
+		} else {
+			video.SubtitlesKey, video.SubtitlesURL = key, url
+		}
+	}
+	video.HasAudio = media.hasAudio
+
 	// publish result
-	if err := ws.queue.PublishResult(ws.cancelContext, animation.NewSuccessResult(task.event.SessionID, url)); err != nil {
+	result := animation.NewSuccessResult(task.event.SessionID, task.event.JobID, video)
+	if err := ws.queue.PublishResult(ws.cancelContext, result); err != nil {
 		ws.log.Error("failed to send message", "err", err)
 		return
 	}
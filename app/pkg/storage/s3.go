@@ -36,18 +36,21 @@ func NewS3(client *s3.Client, bucket string, log *slog.Logger) *S3 {
 	}
 }
 
-func (s *S3) UploadAndPresign(ctx context.Context, outputPath string, sessionId string) (string, error) {
+// UploadAndPresign uploads the file at outputPath under the session's prefix
+// and returns its key and a presigned URL to it.
+func (s *S3) UploadAndPresign(ctx context.Context, outputPath string, sessionId string) (key, url string, err error) {
 
 	videoFile, err := os.Open(outputPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open animation file %w", err)
+		return "", "", fmt.Errorf("failed to open animation file %w", err)
 	}
+	defer videoFile.Close()
 	ext := filepath.Ext(outputPath)
 	if ext == "" {
-		return "", fmt.Errorf("animation file has no extension %w", err)
+		return "", "", fmt.Errorf("animation file has no extension %w", err)
 	}
 
-	key := fmt.Sprintf("manim_outputs/%s/%d%s",
+	key = fmt.Sprintf("manim_outputs/%s/%d%s",
 		sessionId,
 		time.Now().UnixNano(),
 		ext,
@@ -55,10 +58,11 @@ func (s *S3) UploadAndPresign(ctx context.Context, outputPath string, sessionId
 
 	err = s.Upload(ctx, key, videoFile)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return s.PresignGet(ctx, key, time.Minute*3)
+	url, err = s.PresignGet(ctx, key, time.Minute*3)
+	return key, url, err
 
 }
 
@@ -7,6 +7,7 @@ import {
   GenerateSuccess,
   CompileError,
   GenerateError,
+  GenerateProgress,
 } from '../types/types';
 
 export const useEventSource = ({
@@ -85,7 +86,18 @@ export const useEventSource = ({
               resetState()
               break;
             }
-            
+            case 'generate_progress': {
+              const partial = (message.data as GenerateProgress).code;
+              if (editorRef.current) {
+                editorRef.current.setValue(partial);
+              }
+              break;
+            }
+            // This client never asks for a storyboard, and a repaired script
+            // arrives as generate_succeeded, so both only keep the job alive
+            case 'storyboard_ready':
+            case 'repair_attempt':
+              break;
           }
         };
 
@@ -3,11 +3,15 @@ export type EventKind =
   | 'compile_succeeded'
   | 'compile_failed'
   | 'generate_succeeded'
-  | 'generate_failed';
+  | 'generate_failed'
+  | 'generate_progress'
+  | 'storyboard_ready'
+  | 'repair_attempt';
 
 export interface Event<T extends EventKind> {
   kind: T;
   sessionId: string;
+  job_id?: string;
   data: EventData<T>;
 }
 
@@ -21,14 +25,31 @@ export type EventData<T extends EventKind> = T extends 'compile_requested'
   ? GenerateSuccess
   : T extends 'generate_failed'
   ? GenerateError
+  : T extends 'generate_progress'
+  ? GenerateProgress
+  : T extends 'storyboard_ready'
+  ? StoryboardReady
+  : T extends 'repair_attempt'
+  ? RepairAttempt
   : never;
 
 export interface CompileRequest {
   script: string;
+  narration?: NarrationLine[];
+}
+
+export interface NarrationLine {
+  start: number;
+  end: number;
+  text: string;
 }
 
 export interface CompileSuccess {
   video_url: string;
+  video_key?: string;
+  subtitles_url?: string;
+  subtitles_key?: string;
+  has_audio: boolean;
 }
 
 export interface CompileError {
@@ -40,10 +61,48 @@ export interface CompileError {
 
 export interface GenerateSuccess {
   script: string;
+  model?: string;
+  prompt_version?: string;
 }
 
 export interface GenerateError {
   message: string;
   details?: string;
   model: string;
-}
\ No newline at end of file
+  reason?: 'session_budget_exhausted' | 'daily_budget_exhausted' | 'content_flagged';
+  categories?: string[];
+}
+
+// Partial script while the generation is streaming
+export interface GenerateProgress {
+  code: string;
+}
+
+export interface StoryboardBeat {
+  description: string;
+  objects: string[];
+  narration: string;
+  duration: number;
+}
+
+export interface Storyboard {
+  title: string;
+  beats: StoryboardBeat[];
+  warnings: string;
+  valid_input: boolean;
+}
+
+// With review set, the script is only generated once the storyboard is
+// submitted back
+export interface StoryboardReady {
+  storyboard: Storyboard;
+  model: string;
+  review: boolean;
+}
+
+// Automatic attempt to fix a script that failed to compile
+export interface RepairAttempt {
+  attempt: number;
+  max_attempts: number;
+  error: string;
+}
@@ -216,11 +216,18 @@ export class AppStack extends cdk.Stack {
         const resultsBucket = new s3.Bucket(this, 'manimaticVideosBucket', {
             bucketName: 'manimatic-animations-store-bucket',
             blockPublicAccess: s3.BlockPublicAccess.BLOCK_ALL,
-            versioned: true
+            versioned: true,
+            lifecycleRules: [{
+                // Markers of canceled compilations are only checked while the job is queued or running
+                prefix: 'canceled_jobs/',
+                expiration: cdk.Duration.days(1),
+                noncurrentVersionExpiration: cdk.Duration.days(1)
+            }]
         })
 
         // grant access to the EC2 instances 
         resultsBucket.grantRead(apiEC2Instance)
+        resultsBucket.grantPut(apiEC2Instance, 'canceled_jobs/*')
         resultsBucket.grantReadWrite(workerInstance)
         resultsBucket.grantDelete(workerInstance)
 